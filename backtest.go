@@ -0,0 +1,347 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/adshao/go-binance/v2"
+)
+
+const (
+	// backtestInterval is the kline interval of the replay
+	backtestInterval = "1h"
+
+	// klinePageSize is the max number of klines fetched by one request
+	klinePageSize = 1000
+
+	// backtestDateFormat is the format of the date range of the backtest
+	backtestDateFormat = "2006-01-02"
+)
+
+// LoadKlines returns the klines of the symbol, which are opened in the time range.
+// Complete ranges in the past are cached in the directory, if it is not empty.
+func LoadKlines(exchange Exchange, cacheDir, symbol, interval string, from, to time.Time) ([]*binance.Kline, error) {
+	cacheFile := ""
+	if cacheDir != "" && to.Before(time.Now()) {
+		cacheFile = filepath.Join(cacheDir, fmt.Sprintf("%v-%v-%v-%v.json", symbol, interval, from.Format(backtestDateFormat), to.Format(backtestDateFormat)))
+		if b, err := ioutil.ReadFile(cacheFile); err == nil {
+			var klines []*binance.Kline
+			if err := json.Unmarshal(b, &klines); err == nil {
+				return klines, nil
+			}
+		}
+	}
+
+	var klines []*binance.Kline
+	start, end := from.UnixNano()/int64(time.Millisecond), to.UnixNano()/int64(time.Millisecond)
+	for {
+		page, err := exchange.Klines(symbol, interval, start, klinePageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range page {
+			if k.OpenTime < end {
+				klines = append(klines, k)
+			}
+		}
+		if len(page) < klinePageSize || page[len(page)-1].OpenTime >= end {
+			break
+		}
+		start = page[len(page)-1].OpenTime + 1
+	}
+
+	if cacheFile != "" {
+		if err := os.MkdirAll(cacheDir, 0700); err != nil {
+			return klines, err
+		}
+		b, err := json.Marshal(klines)
+		if err != nil {
+			return klines, err
+		}
+		if err := ioutil.WriteFile(cacheFile, b, 0600); err != nil {
+			return klines, err
+		}
+	}
+	return klines, nil
+}
+
+// replayMarket is the market of a backtest. The price of the symbol is set by the replay,
+// all other calls are passed to the underlying exchange.
+type replayMarket struct {
+	Exchange
+	symbol string
+	price  F
+}
+
+func (m *replayMarket) Prices(symbol string) ([]*binance.SymbolPrice, error) {
+	if symbol != m.symbol {
+		return m.Exchange.Prices(symbol)
+	}
+	return []*binance.SymbolPrice{{Symbol: symbol, Price: m.price.String()}}, nil
+}
+
+// path returns the prices the kline went through: open, the extreme against the direction,
+// the extreme in the direction and close
+func path(k *binance.Kline) []F {
+	if FromS(k.Close).Cmp(FromS(k.Open)) >= 0 {
+		return []F{FromS(k.Open), FromS(k.Low), FromS(k.High), FromS(k.Close)}
+	}
+	return []F{FromS(k.Open), FromS(k.High), FromS(k.Low), FromS(k.Close)}
+}
+
+// backtestParams is the strategy of a backtest: a buy limit for the invest amount
+// at the multiplier of the basePrice, followed by a sell wall up to the max multiplier
+type backtestParams struct {
+	symbol      *binance.Symbol
+	invest      F // in the quote asset
+	buyMult     F
+	sellMaxMult F
+	shape       wallShape
+}
+
+// backtestFill is an executed order of the backtest
+type backtestFill struct {
+	time  time.Time
+	side  string
+	qty   F
+	price F
+}
+
+// backtestResult are the performance figures of a backtest
+type backtestResult struct {
+	basePrice   F
+	klines      int
+	fills       []backtestFill
+	start       F // value of the wallet in the quote asset
+	end         F
+	hold        F // value of buying at the basePrice and holding until the end
+	maxDrawdown F
+	inPosition  time.Duration
+	duration    time.Duration
+}
+
+// runBacktest replays the klines against a simulated order book. The orders are constructed
+// like the ones of Buy and SellWall, with the open price of the first kline as basePrice.
+func runBacktest(market Exchange, params backtestParams, klines []*binance.Kline) (*backtestResult, error) {
+	if len(klines) == 0 {
+		return nil, fmt.Errorf("no klines to replay")
+	}
+	symbol := params.symbol.Symbol
+	replay := &replayMarket{Exchange: market, symbol: symbol, price: FromS(klines[0].Open)}
+	paper := NewPaperExchange(replay, map[string]F{params.symbol.QuoteAsset: params.invest})
+	filters := NewSymbolFilters(params.symbol)
+
+	result := &backtestResult{
+		basePrice:   replay.price,
+		klines:      len(klines),
+		start:       params.invest,
+		maxDrawdown: FromI(0),
+	}
+	result.hold = params.invest.Div(result.basePrice).Mult(FromS(klines[len(klines)-1].Close))
+
+	qty, limit := buyLimit(result.basePrice, params.buyMult, params.invest)
+	qty = filters.Quantity(qty)
+	limit = filters.Price(limit, binance.SideTypeBuy)
+	if err := filters.Check(qty, limit); err != nil {
+		return nil, fmt.Errorf("buy not possible for %v of %v: %v", qty.StringCompact(), symbol, err)
+	}
+	if _, err := paper.CreateOrder(OrderRequest{
+		Symbol:      symbol,
+		Side:        binance.SideTypeBuy,
+		Type:        binance.OrderTypeLimit,
+		TimeInForce: binance.TimeInForceTypeGTC,
+		Quantity:    qty.String(),
+		Price:       limit.String(),
+	}); err != nil {
+		return nil, err
+	}
+
+	wallPlaced := false
+	peak := params.invest
+	trades := 0
+	for _, k := range klines {
+		openTime := time.Unix(0, k.OpenTime*int64(time.Millisecond))
+		for _, price := range path(k) {
+			replay.price = price
+			paper.Prices(symbol)
+			trades = result.recordFills(paper, symbol, trades, openTime)
+
+			base, _, _ := backtestBalances(paper, params.symbol)
+			if !wallPlaced && base.Sign() > 0 {
+				if err := placeBacktestWall(paper, params, result.basePrice, base, filters); err != nil {
+					return nil, err
+				}
+				wallPlaced = true
+				trades = result.recordFills(paper, symbol, trades, openTime)
+			}
+
+			// the drawdown is sampled at every price of the path, so the lows within a kline count
+			base, quote, err := backtestBalances(paper, params.symbol)
+			if err != nil {
+				return nil, err
+			}
+			value := quote.Add(base.Mult(price))
+			if value.Cmp(peak) > 0 {
+				peak = value
+			}
+			if drawdown := peak.Sub(value).Div(peak); drawdown.Cmp(result.maxDrawdown) > 0 {
+				result.maxDrawdown = drawdown
+			}
+		}
+
+		base, quote, err := backtestBalances(paper, params.symbol)
+		if err != nil {
+			return nil, err
+		}
+		value := quote.Add(base.Mult(FromS(k.Close)))
+		// a rest below the filters of the symbol can not be sold and is no position
+		interval := time.Duration(k.CloseTime+1-k.OpenTime) * time.Millisecond
+		if filters.Check(base, FromS(k.Close)) == nil {
+			result.inPosition += interval
+		}
+		result.duration += interval
+		result.end = value
+	}
+	return result, nil
+}
+
+// placeBacktestWall places the sell wall for the bought quantity from the highest price down
+func placeBacktestWall(paper *PaperExchange, params backtestParams, basePrice, qty F, filters SymbolFilters) error {
+	orders := params.shape.orders(qty, basePrice, basePrice.Mult(params.sellMaxMult), filters)
+	for i := len(orders) - 1; i >= 0; i-- {
+		if orders[i].err != nil {
+			continue
+		}
+		if _, err := paper.CreateOrder(OrderRequest{
+			Symbol:      params.symbol.Symbol,
+			Side:        binance.SideTypeSell,
+			Type:        binance.OrderTypeLimit,
+			TimeInForce: binance.TimeInForceTypeGTC,
+			Quantity:    orders[i].qty.String(),
+			Price:       orders[i].price.String(),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordFills adds the trades after the first ones to the fills and returns the number of trades
+func (result *backtestResult) recordFills(paper *PaperExchange, symbol string, known int, at time.Time) int {
+	trades, _ := paper.TradesFrom(symbol, 0, known+klinePageSize)
+	for _, t := range trades[known:] {
+		result.fills = append(result.fills, backtestFill{
+			time:  at,
+			side:  sideOf(t),
+			qty:   FromS(t.Quantity),
+			price: FromS(t.Price),
+		})
+	}
+	return len(trades)
+}
+
+// backtestBalances returns the total balances of the base and quote asset of the simulation
+func backtestBalances(paper *PaperExchange, symbol *binance.Symbol) (base, quote F, err error) {
+	account, err := paper.Account()
+	if err != nil {
+		return F{}, F{}, err
+	}
+	base, quote = FromI(0), FromI(0)
+	for _, b := range account.Balances {
+		total := FromS(b.Free).Add(FromS(b.Locked))
+		switch b.Asset {
+		case symbol.BaseAsset:
+			base = total
+		case symbol.QuoteAsset:
+			quote = total
+		}
+	}
+	return base, quote, nil
+}
+
+// backtestArgs parses the arguments of the backtest: the date range,
+// followed by optional buy and sell max multipliers, e.g. "2021-01-01 2021-06-30 0.9 3"
+func (sess *Session) backtestArgs(arg string) (from, to time.Time, buyMult, sellMaxMult F, err error) {
+	args := strings.Fields(arg)
+	buyMult, sellMaxMult = sess.buyMaxMult, sess.sellMaxMult
+	if len(args) < 2 || len(args) > 4 {
+		return from, to, buyMult, sellMaxMult, fmt.Errorf("missing date range")
+	}
+	if from, err = time.ParseInLocation(backtestDateFormat, args[0], time.Local); err != nil {
+		return from, to, buyMult, sellMaxMult, fmt.Errorf("invalid date: %q", args[0])
+	}
+	if to, err = time.ParseInLocation(backtestDateFormat, args[1], time.Local); err != nil {
+		return from, to, buyMult, sellMaxMult, fmt.Errorf("invalid date: %q", args[1])
+	}
+	// the end date is included
+	to = to.AddDate(0, 0, 1)
+	if !from.Before(to) {
+		return from, to, buyMult, sellMaxMult, fmt.Errorf("the start has to be before the end")
+	}
+	for i, mult := range []*F{&buyMult, &sellMaxMult} {
+		if len(args) > i+2 {
+			if *mult = FromS(args[i+2]); !mult.Valid() || mult.Sign() <= 0 {
+				return from, to, buyMult, sellMaxMult, fmt.Errorf("invalid multiplier: %q", args[i+2])
+			}
+		}
+	}
+	return from, to, buyMult, sellMaxMult, nil
+}
+
+// Backtest replays the klines of the selected symbol with a buy for the invest amount
+// and a sell wall, using the session parameters, e.g. "2021-01-01 2021-06-30 0.9 3"
+func (sess *Session) Backtest(arg string) {
+	if sess.selected == "" {
+		sess.Answer("NO SYMBOL SELECTED!")
+		return
+	}
+	from, to, buyMult, sellMaxMult, err := sess.backtestArgs(arg)
+	if err != nil {
+		sess.Answerf("USAGE: backtest <from yyyy-mm-dd> <to yyyy-mm-dd> [buy multiplier] [sell max multiplier]: %v", err)
+		return
+	}
+	symbol := sess.allSymbols[sess.selected]
+	if symbol == nil {
+		sess.Answerf("NO EXCHANGE INFO FOR %v", sess.selected)
+		return
+	}
+
+	klines, err := LoadKlines(sess.exchange, sess.klineCache, sess.selected, backtestInterval, from, to)
+	if err != nil {
+		sess.Answerf("ERROR ON LOADING KLINES: %v", err)
+		if len(klines) == 0 {
+			return
+		}
+	}
+
+	// the invest amount is converted with the current price of the quote asset
+	params := backtestParams{
+		symbol:      symbol,
+		invest:      sess.maxInvest.Div(sess.quoteRate).FloorTo(minPrecision),
+		buyMult:     buyMult,
+		sellMaxMult: sellMaxMult,
+		shape:       sess.wallShape,
+	}
+	result, err := runBacktest(sess.exchange, params, klines)
+	if err != nil {
+		sess.Answerf("BACKTEST NOT POSSIBLE: %v", err)
+		return
+	}
+
+	q := symbol.QuoteAsset
+	sess.Answerf("%v, %v klines of %v from %v to %v", sess.selected, result.klines, backtestInterval, from.Format(backtestDateFormat), to.AddDate(0, 0, -1).Format(backtestDateFormat))
+	sess.Answerf("buy %v %v at %v of %v, sell wall up to %v (%v)", params.invest, q, buyMult.FormatPercent(), result.basePrice, sellMaxMult.FormatPercent(), params.shape)
+	for _, f := range result.fills {
+		sess.Answerf("  %v %v %v @%v", f.time.Local().Format("2006-01-02 15:04"), f.side, f.qty.StringCompact(), f.price)
+	}
+	gain := result.end.Sub(result.start)
+	sess.Answerf("   return: %v %v (%v)", gain, q, gain.Div(result.start).FormatPercent())
+	sess.Answerf("     hold: %v", result.hold.Sub(result.start).Div(result.start).FormatPercent())
+	sess.Answerf(" drawdown: %v", result.maxDrawdown.FormatPercent())
+	sess.Answerf(" position: %v of the time (%v)", share(FromI(int(result.inPosition)), FromI(int(result.duration))), result.inPosition)
+}
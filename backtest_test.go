@@ -0,0 +1,137 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adshao/go-binance/v2"
+	"github.com/smancke/trading-shell/binancefake"
+	"github.com/smancke/trading-shell/config"
+)
+
+// hourlyKlines returns n klines of one hour from the start, with the prices of the function
+func hourlyKlines(start time.Time, n int, price func(i int) (open, high, low, close string)) []binance.Kline {
+	hour := time.Hour.Milliseconds()
+	first := start.UnixNano() / int64(time.Millisecond)
+	klines := make([]binance.Kline, n)
+	for i := range klines {
+		open, high, low, close := price(i)
+		klines[i] = binance.Kline{OpenTime: first + int64(i)*hour, CloseTime: first + int64(i+1)*hour - 1, Open: open, High: high, Low: low, Close: close}
+	}
+	return klines
+}
+
+func TestBacktestDrawdownWithinKline(t *testing.T) {
+	srv := binancefake.NewServer()
+	t.Cleanup(srv.Close)
+	srv.AddSymbol("XYZBTC", "XYZ", "BTC")
+
+	params := backtestParams{
+		symbol:      &binance.Symbol{Symbol: "XYZBTC", BaseAsset: "XYZ", QuoteAsset: "BTC"},
+		invest:      FromI(1),
+		buyMult:     FromI(1),
+		sellMaxMult: FromI(2),
+		shape:       defaultWallShape(),
+	}
+	hour := int64(3600000)
+	klines := []*binance.Kline{
+		{OpenTime: 0, CloseTime: hour - 1, Open: "1", High: "1", Low: "1", Close: "1"},
+		// the low is only reached within the kline
+		{OpenTime: hour, CloseTime: 2*hour - 1, Open: "1", High: "1", Low: "0.5", Close: "1"},
+	}
+	result, err := runBacktest(NewBinanceExchange(srv.BinanceClient()), params, klines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.fills) != 1 || result.fills[0].side != string(binance.SideTypeBuy) {
+		t.Fatalf("expected the buy to be filled, got %+v", result.fills)
+	}
+	if result.maxDrawdown.Cmp(FromS("0.49")) < 0 || result.maxDrawdown.Cmp(FromS("0.51")) > 0 {
+		t.Errorf("expected a drawdown of 50%%, got %v", result.maxDrawdown.FormatPercent())
+	}
+}
+
+func TestLoadKlines(t *testing.T) {
+	srv := binancefake.NewServer()
+	t.Cleanup(srv.Close)
+	from := time.Date(2021, 1, 1, 0, 0, 0, 0, time.Local)
+	to := from.Add(2500 * time.Hour)
+	// more klines than in the range
+	srv.SetKlines("XYZBTC", hourlyKlines(from, 2600, func(i int) (string, string, string, string) {
+		return "1", "1", "1", "1"
+	}))
+	cacheDir := filepath.Join(t.TempDir(), "klines")
+
+	// three pages of 1000, the last one is only used up to the end of the range
+	exchange := &klineCounter{Exchange: NewBinanceExchange(srv.BinanceClient())}
+	klines, err := LoadKlines(exchange, cacheDir, "XYZBTC", "1h", from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(klines) != 2500 || exchange.requests != 3 {
+		t.Fatalf("expected 2500 klines by 3 requests, got %v by %v", len(klines), exchange.requests)
+	}
+	if end := to.UnixNano() / int64(time.Millisecond); klines[len(klines)-1].OpenTime >= end {
+		t.Errorf("expected the klines before the end, got %v", klines[len(klines)-1].OpenTime)
+	}
+
+	// the range in the past is read from the cache
+	cached, err := LoadKlines(exchange, cacheDir, "XYZBTC", "1h", from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cached) != 2500 || cached[0].OpenTime != klines[0].OpenTime || exchange.requests != 3 {
+		t.Errorf("expected the 2500 klines from the cache, got %v, %v requests", len(cached), exchange.requests)
+	}
+}
+
+func TestBacktestCommand(t *testing.T) {
+	day := time.Date(2021, 1, 1, 0, 0, 0, 0, time.Local)
+	shell := newTestShell(t, func(srv *binancefake.Server, c *config.Config) {
+		// the price rises to 0.00005 and falls back
+		prices := [][4]string{
+			{"0.00001", "0.00001", "0.00001", "0.00001"},
+			{"0.00001", "0.00005", "0.00001", "0.00004"},
+			{"0.00004", "0.00004", "0.00001", "0.00001"},
+		}
+		srv.SetKlines("XYZBTC", hourlyKlines(day, len(prices), func(i int) (string, string, string, string) {
+			return prices[i][0], prices[i][1], prices[i][2], prices[i][3]
+		}))
+	})
+	shell.run("xyzbtc")
+
+	tests := []struct {
+		arg      string
+		expected []string
+	}{
+		{"2021-01-01", []string{"USAGE: backtest", "missing date range"}},
+		{"2021-01-02 2021-01-01", []string{"USAGE: backtest", "the start has to be before the end"}},
+		{"2021-01-01 2021-01-01 x", []string{"USAGE: backtest", "invalid multiplier"}},
+		// 50€ at 50000€ are 0.001 BTC for 83.3 XYZ at the limit of 120%, which is filled at once
+		// at the open price. The bought XYZ are sold by the wall up to 450%
+		{"2021-01-01 2021-01-01", []string{
+			"XYZBTC, 3 klines of 1h from 2021-01-01 to 2021-01-01",
+			"buy 0.00100000 BTC at 120.00% of 0.00001000, sell wall up to 450.00%",
+			"BUY 83.3 @0.00001000",
+			"SELL 20.8 @0.00004500",
+			"return: 0.00182208 BTC (182.21%)",
+			"position: 33.33% of the time",
+		}},
+		// the buy below the price is not filled
+		{"2021-01-01 2021-01-01 0.5 2", []string{
+			"buy 0.00100000 BTC at 50.00% of 0.00001000, sell wall up to 200.00%",
+			"return: 0.00000000 BTC (0.00%)",
+			"drawdown: 0.00%",
+		}},
+	}
+	for _, test := range tests {
+		out := shell.run("backtest " + test.arg)
+		for _, e := range test.expected {
+			if !strings.Contains(out, e) {
+				t.Errorf("%q: expected %q, got:\n%v", test.arg, e, out)
+			}
+		}
+	}
+}
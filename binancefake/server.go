@@ -0,0 +1,498 @@
+// Package binancefake provides a local fake of the binance REST and websocket api
+// for running the shell without network access, e.g. in integration tests.
+package binancefake
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/adshao/go-binance/v2"
+	"github.com/adshao/go-binance/v2/common"
+	"github.com/shopspring/decimal"
+	"github.com/smancke/trading-shell/orderbook"
+)
+
+// Server is a httptest server implementing the subset of the binance api
+// used by the shell. The state of the server can be scripted by its methods.
+type Server struct {
+	*httptest.Server
+	mutex     sync.Mutex
+	symbols   []binance.Symbol
+	prices    map[string]string
+	avgPrices map[string]string
+	stats     map[string]*binance.PriceChangeStats
+	klines    map[string][]binance.Kline
+	book      *orderbook.Book // the orders and balances of the account
+	nextTrade int64
+
+	nextListenKey int64
+	subscribers   map[*subscriber]bool
+}
+
+// NewServer starts a new fake server. It has to be closed after usage.
+func NewServer() *Server {
+	s := &Server{
+		prices:    make(map[string]string),
+		avgPrices: make(map[string]string),
+		stats:     make(map[string]*binance.PriceChangeStats),
+		klines:    make(map[string][]binance.Kline),
+		book:      orderbook.New("fake"),
+
+		subscribers: make(map[*subscriber]bool),
+	}
+	s.book.OnOrder = func(update binance.WsOrderUpdate) {
+		s.publishUserEvent(binance.UserDataEventTypeExecutionReport, &update)
+	}
+	s.book.OnBalances = func(update binance.WsAccountUpdateList) {
+		s.publishUserEvent(binance.UserDataEventTypeOutboundAccountPosition, &update)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/exchangeInfo", s.handleExchangeInfo)
+	mux.HandleFunc("/api/v3/ticker/24hr", s.handleTicker24hr)
+	mux.HandleFunc("/api/v3/ticker/price", s.handleTickerPrice)
+	mux.HandleFunc("/api/v3/avgPrice", s.handleAvgPrice)
+	mux.HandleFunc("/api/v3/account", s.handleAccount)
+	mux.HandleFunc("/api/v3/order", s.handleOrder)
+	mux.HandleFunc("/api/v3/order/oco", s.handleOCO)
+	mux.HandleFunc("/api/v3/openOrders", s.handleOpenOrders)
+	mux.HandleFunc("/api/v3/allOrders", s.handleAllOrders)
+	mux.HandleFunc("/api/v3/myTrades", s.handleMyTrades)
+	mux.HandleFunc("/api/v3/klines", s.handleKlines)
+	mux.HandleFunc("/api/v3/userDataStream", s.handleUserDataStream)
+	mux.HandleFunc("/stream", s.handleStream)
+	mux.HandleFunc("/ws/", s.handleUserStream)
+	s.Server = httptest.NewServer(withDeleteForm(mux))
+	return s
+}
+
+// withDeleteForm parses the form body of delete requests, which is ignored by the http package,
+// but used by the binance client, e.g. for canceling an order
+func withDeleteForm(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			r.ParseForm()
+			body, _ := ioutil.ReadAll(r.Body)
+			values, _ := url.ParseQuery(string(body))
+			for key, v := range values {
+				r.Form[key] = append(r.Form[key], v...)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// BinanceClient returns a binance client, which talks to this server
+func (s *Server) BinanceClient() *binance.Client {
+	client := binance.NewClient("fake-key", "fake-secret")
+	client.BaseURL = s.URL
+	return client
+}
+
+// AddSymbol registers a trading pair with optional exchange filters
+func (s *Server) AddSymbol(symbol, baseAsset, quoteAsset string, filters ...map[string]interface{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	symbolInfo := binance.Symbol{
+		Symbol:               symbol,
+		Status:               "TRADING",
+		BaseAsset:            baseAsset,
+		BaseAssetPrecision:   8,
+		QuoteAsset:           quoteAsset,
+		QuotePrecision:       8,
+		QuoteAssetPrecision:  8,
+		OrderTypes:           []string{"LIMIT", "LIMIT_MAKER", "MARKET", "STOP_LOSS_LIMIT", "TAKE_PROFIT_LIMIT"},
+		OcoAllowed:           true,
+		IsSpotTradingAllowed: true,
+		Filters:              filters,
+		Permissions:          []string{"SPOT"},
+	}
+	s.symbols = append(s.symbols, symbolInfo)
+	s.book.AddSymbol(symbolInfo)
+}
+
+// SetPrice sets the current price of the symbol and publishes it as trade to the stream subscribers.
+// The open orders crossed by the price are filled.
+// The average price and the 24h stats are initialized with the same price, if not set before.
+func (s *Server) SetPrice(symbol, price string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.prices[symbol] = price
+	if _, exist := s.avgPrices[symbol]; !exist {
+		s.avgPrices[symbol] = price
+	}
+	stats, exist := s.stats[symbol]
+	if !exist {
+		stats = &binance.PriceChangeStats{
+			Symbol:             symbol,
+			PriceChange:        "0",
+			PriceChangePercent: "0",
+			WeightedAvgPrice:   price,
+			OpenPrice:          price,
+			HighPrice:          price,
+			LowPrice:           price,
+			Volume:             "0",
+			QuoteVolume:        "0",
+		}
+		s.stats[symbol] = stats
+	}
+	stats.LastPrice = price
+	s.publishTrade(symbol, price)
+	s.book.Match(symbol, parseDecimal(price))
+}
+
+// SetAvgPrice sets the recent average price of the symbol
+func (s *Server) SetAvgPrice(symbol, price string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.avgPrices[symbol] = price
+}
+
+// SetStats sets the 24h price change statistics of a symbol
+func (s *Server) SetStats(stats binance.PriceChangeStats) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.stats[stats.Symbol] = &stats
+}
+
+// SetKlines sets the candlesticks of the symbol, ordered by their open time.
+// The interval of the request is only used for the time range of the limit, the klines are not aggregated.
+func (s *Server) SetKlines(symbol string, klines []binance.Kline) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.klines[symbol] = klines
+}
+
+// AddTrade adds a trade of the account, e.g. from the past. The id is assigned by the server.
+func (s *Server) AddTrade(trade binance.TradeV3) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.book.AddTrade(trade)
+}
+
+// SetBalance sets the free and locked balance of an asset
+func (s *Server) SetBalance(asset, free, locked string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.book.SetBalance(asset, parseDecimal(free), parseDecimal(locked))
+}
+
+// Orders returns a copy of all orders known by the server
+func (s *Server) Orders() []binance.Order {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var result []binance.Order
+	for _, o := range s.book.Orders("", false) {
+		result = append(result, *o)
+	}
+	return result
+}
+
+// FillOrder fills the open order at the given price, regardless of the current price
+func (s *Server) FillOrder(orderID int64, price string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.book.Fill(orderID, parseDecimal(price))
+}
+
+func (s *Server) handleExchangeInfo(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	writeJSON(w, &binance.ExchangeInfo{
+		Timezone:   "UTC",
+		ServerTime: nowMillis(),
+		Symbols:    s.symbols,
+	})
+}
+
+func (s *Server) handleTicker24hr(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if symbol := r.FormValue("symbol"); symbol != "" {
+		stats, exist := s.stats[symbol]
+		if !exist {
+			writeError(w, -1121, "Invalid symbol.")
+			return
+		}
+		writeJSON(w, stats)
+		return
+	}
+	var result []*binance.PriceChangeStats
+	for _, stats := range s.stats {
+		result = append(result, stats)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Symbol < result[j].Symbol })
+	writeJSON(w, result)
+}
+
+func (s *Server) handleTickerPrice(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if symbol := r.FormValue("symbol"); symbol != "" {
+		price, exist := s.prices[symbol]
+		if !exist {
+			writeError(w, -1121, "Invalid symbol.")
+			return
+		}
+		writeJSON(w, &binance.SymbolPrice{Symbol: symbol, Price: price})
+		return
+	}
+	var result []*binance.SymbolPrice
+	for symbol, price := range s.prices {
+		result = append(result, &binance.SymbolPrice{Symbol: symbol, Price: price})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Symbol < result[j].Symbol })
+	writeJSON(w, result)
+}
+
+func (s *Server) handleAvgPrice(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	price, exist := s.avgPrices[r.FormValue("symbol")]
+	if !exist {
+		writeError(w, -1121, "Invalid symbol.")
+		return
+	}
+	writeJSON(w, &binance.AvgPrice{Mins: 5, Price: price})
+}
+
+func (s *Server) handleAccount(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	account := &binance.Account{
+		CanTrade:    true,
+		AccountType: "SPOT",
+		UpdateTime:  uint64(nowMillis()),
+		Permissions: []string{"SPOT"},
+	}
+	account.Balances = s.book.Balances()
+	writeJSON(w, account)
+}
+
+func (s *Server) handleOrder(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	id, _ := strconv.ParseInt(r.FormValue("orderId"), 10, 64)
+	switch r.Method {
+	case http.MethodPost:
+		// the order is matched against the current price, without a price market orders are rejected
+		order, err := s.book.CreateOrder(orderbook.OrderRequest{
+			Symbol:        r.FormValue("symbol"),
+			Side:          binance.SideType(r.FormValue("side")),
+			Type:          binance.OrderType(r.FormValue("type")),
+			TimeInForce:   binance.TimeInForceType(r.FormValue("timeInForce")),
+			Quantity:      r.FormValue("quantity"),
+			QuoteOrderQty: r.FormValue("quoteOrderQty"),
+			Price:         r.FormValue("price"),
+			StopPrice:     r.FormValue("stopPrice"),
+		}, parseDecimal(s.prices[r.FormValue("symbol")]))
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+		writeJSON(w, order)
+	case http.MethodGet:
+		o, exist := s.book.Order(r.FormValue("symbol"), id)
+		if !exist {
+			writeError(w, -2013, "Order does not exist.")
+			return
+		}
+		writeJSON(w, o)
+	case http.MethodDelete:
+		// canceling one leg cancels the whole order list
+		o, err := s.book.Cancel(r.FormValue("symbol"), id)
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+		writeJSON(w, cancelResponse(o))
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleOCO(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	oco, err := s.book.CreateOCO(orderbook.OCORequest{
+		Symbol:         r.FormValue("symbol"),
+		Side:           binance.SideType(r.FormValue("side")),
+		Quantity:       r.FormValue("quantity"),
+		Price:          r.FormValue("price"),
+		StopPrice:      r.FormValue("stopPrice"),
+		StopLimitPrice: r.FormValue("stopLimitPrice"),
+	}, parseDecimal(s.prices[r.FormValue("symbol")]))
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	writeJSON(w, oco)
+}
+
+func (s *Server) handleOpenOrders(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	symbol := r.FormValue("symbol")
+	switch r.Method {
+	case http.MethodGet:
+		result := []*binance.Order{}
+		writeJSON(w, append(result, s.book.Orders(symbol, true)...))
+	case http.MethodDelete:
+		result := []*binance.CancelOrderResponse{}
+		for _, o := range s.book.CancelOpenOrders(symbol) {
+			result = append(result, cancelResponse(o))
+		}
+		if len(result) == 0 {
+			writeError(w, -2011, "Unknown order sent.")
+			return
+		}
+		writeJSON(w, result)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleAllOrders(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	result := append([]*binance.Order{}, s.book.Orders(r.FormValue("symbol"), false)...)
+	if limit, err := strconv.Atoi(r.FormValue("limit")); err == nil && len(result) > limit {
+		result = result[len(result)-limit:]
+	}
+	writeJSON(w, result)
+}
+
+func (s *Server) handleMyTrades(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	fromID, err := strconv.ParseInt(r.FormValue("fromId"), 10, 64)
+	hasFromID := err == nil
+	result := []*binance.TradeV3{}
+	for _, t := range s.book.Trades(r.FormValue("symbol")) {
+		if !hasFromID || t.ID >= fromID {
+			result = append(result, t)
+		}
+	}
+	// the most recent trades, or the first ones starting with the id
+	if limit, err := strconv.Atoi(r.FormValue("limit")); err == nil && len(result) > limit {
+		if hasFromID {
+			result = result[:limit]
+		} else {
+			result = result[len(result)-limit:]
+		}
+	}
+	writeJSON(w, result)
+}
+
+// handleKlines lists the candlesticks as arrays, like the binance api
+func (s *Server) handleKlines(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	startTime, err := strconv.ParseInt(r.FormValue("startTime"), 10, 64)
+	if err != nil {
+		startTime = 0
+	}
+	endTime, err := strconv.ParseInt(r.FormValue("endTime"), 10, 64)
+	if err != nil {
+		endTime = math.MaxInt64
+	}
+	limit, err := strconv.Atoi(r.FormValue("limit"))
+	if err != nil {
+		limit = 500
+	}
+	// like binance, the klines of limit intervals from the start are returned, also if there are gaps
+	if interval := intervalMillis(r.FormValue("interval")); interval > 0 && r.FormValue("startTime") != "" {
+		if end := startTime + int64(limit)*interval - 1; end < endTime {
+			endTime = end
+		}
+	}
+
+	result := [][]interface{}{}
+	for _, k := range s.klines[r.FormValue("symbol")] {
+		if k.OpenTime < startTime || k.OpenTime > endTime || len(result) >= limit {
+			continue
+		}
+		result = append(result, []interface{}{
+			k.OpenTime, k.Open, k.High, k.Low, k.Close, k.Volume,
+			k.CloseTime, k.QuoteAssetVolume, k.TradeNum, k.TakerBuyBaseAssetVolume, k.TakerBuyQuoteAssetVolume, "0",
+		})
+	}
+	writeJSON(w, result)
+}
+
+func cancelResponse(o *binance.Order) *binance.CancelOrderResponse {
+	return &binance.CancelOrderResponse{
+		Symbol:                   o.Symbol,
+		OrigClientOrderID:        o.ClientOrderID,
+		OrderID:                  o.OrderID,
+		OrderListID:              o.OrderListId,
+		ClientOrderID:            o.ClientOrderID,
+		TransactTime:             nowMillis(),
+		Price:                    o.Price,
+		OrigQuantity:             o.OrigQuantity,
+		ExecutedQuantity:         o.ExecutedQuantity,
+		CummulativeQuoteQuantity: o.CummulativeQuoteQuantity,
+		Status:                   o.Status,
+		TimeInForce:              o.TimeInForce,
+		Type:                     o.Type,
+		Side:                     o.Side,
+	}
+}
+
+// intervalMillis returns the length of a kline interval, e.g. "1m" or "4h", or 0 if unknown
+func intervalMillis(interval string) int64 {
+	units := map[byte]int64{'m': 60 * 1000, 'h': 60 * 60 * 1000, 'd': 24 * 60 * 60 * 1000, 'w': 7 * 24 * 60 * 60 * 1000}
+	if len(interval) < 2 {
+		return 0
+	}
+	n, err := strconv.ParseInt(interval[:len(interval)-1], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return n * units[interval[len(interval)-1]]
+}
+
+// parseDecimal parses a decimal string, invalid and empty values are zero
+func parseDecimal(value string) decimal.Decimal {
+	d, err := decimal.NewFromString(value)
+	if err != nil {
+		return decimal.Zero
+	}
+	return d
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeAPIError writes the error of the order book, other errors are internal
+func writeAPIError(w http.ResponseWriter, err error) {
+	if apiErr, ok := err.(*common.APIError); ok {
+		writeError(w, apiErr.Code, apiErr.Message)
+		return
+	}
+	writeError(w, -1000, err.Error())
+}
+
+func writeError(w http.ResponseWriter, code int64, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(&common.APIError{Code: code, Message: msg})
+}
+
+func nowMillis() int64 {
+	return time.Now().UnixNano() / int64(time.Millisecond)
+}
@@ -0,0 +1,191 @@
+package binancefake
+
+import (
+	"context"
+	"testing"
+
+	"github.com/adshao/go-binance/v2"
+	"github.com/adshao/go-binance/v2/common"
+)
+
+func newTestServer(t *testing.T) (*Server, *binance.Client) {
+	s := NewServer()
+	t.Cleanup(s.Close)
+	s.AddSymbol("XYZBTC", "XYZ", "BTC")
+	s.SetPrice("XYZBTC", "0.00001000")
+	s.SetBalance("BTC", "0.01", "0")
+	s.SetBalance("XYZ", "1000", "0")
+	return s, s.BinanceClient()
+}
+
+func assertBalance(t *testing.T, client *binance.Client, asset, free, locked string) {
+	t.Helper()
+	account, err := client.NewGetAccountService().Do(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range account.Balances {
+		if b.Asset == asset {
+			if parseDecimal(b.Free).Cmp(parseDecimal(free)) != 0 || parseDecimal(b.Locked).Cmp(parseDecimal(locked)) != 0 {
+				t.Errorf("balance of %v: expected %v free, %v locked, got %v free, %v locked", asset, free, locked, b.Free, b.Locked)
+			}
+			return
+		}
+	}
+	t.Errorf("no balance of %v", asset)
+}
+
+func limitOrder(client *binance.Client, side binance.SideType, qty, price string) (*binance.CreateOrderResponse, error) {
+	return client.NewCreateOrderService().Symbol("XYZBTC").Side(side).Type(binance.OrderTypeLimit).
+		TimeInForce(binance.TimeInForceTypeGTC).Quantity(qty).Price(price).Do(context.Background())
+}
+
+func assertAPIError(t *testing.T, err error, code int64) {
+	t.Helper()
+	apiErr, ok := err.(*common.APIError)
+	if !ok || apiErr.Code != code {
+		t.Errorf("expected api error %v, got %v", code, err)
+	}
+}
+
+func TestLimitOrderLocksFunds(t *testing.T) {
+	s, client := newTestServer(t)
+
+	order, err := limitOrder(client, binance.SideTypeBuy, "100", "0.00000900")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertBalance(t, client, "BTC", "0.0091", "0.0009")
+
+	// filled below the limit, the rest of the locked amount is returned
+	if err := s.FillOrder(order.OrderID, "0.00000800"); err != nil {
+		t.Fatal(err)
+	}
+	assertBalance(t, client, "BTC", "0.0092", "0")
+	assertBalance(t, client, "XYZ", "1100", "0")
+	if err := s.FillOrder(order.OrderID, "0.00000800"); err == nil {
+		t.Error("expected an error on filling a filled order")
+	}
+
+	order, err = limitOrder(client, binance.SideTypeSell, "500", "0.00002000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertBalance(t, client, "XYZ", "600", "500")
+	if _, err := client.NewCancelOrderService().Symbol("XYZBTC").OrderID(order.OrderID).Do(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	assertBalance(t, client, "XYZ", "1100", "0")
+}
+
+func TestInsufficientBalance(t *testing.T) {
+	_, client := newTestServer(t)
+
+	_, err := limitOrder(client, binance.SideTypeSell, "1000.1", "0.00002000")
+	assertAPIError(t, err, -2010)
+	_, err = limitOrder(client, binance.SideTypeBuy, "1001", "0.00001000")
+	assertAPIError(t, err, -2010)
+	assertBalance(t, client, "BTC", "0.01", "0")
+	assertBalance(t, client, "XYZ", "1000", "0")
+}
+
+func TestMarketOrder(t *testing.T) {
+	s, client := newTestServer(t)
+
+	order, err := client.NewCreateOrderService().Symbol("XYZBTC").Side(binance.SideTypeBuy).Type(binance.OrderTypeMarket).
+		QuoteOrderQty("0.001").Do(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Status != binance.OrderStatusTypeFilled || order.ExecutedQuantity != "100.00000000" || order.CummulativeQuoteQuantity != "0.00100000" {
+		t.Errorf("unexpected market order: %+v", order)
+	}
+	assertBalance(t, client, "BTC", "0.009", "0")
+	assertBalance(t, client, "XYZ", "1100", "0")
+
+	// no market price
+	s.AddSymbol("ABCBTC", "ABC", "BTC")
+	_, err = client.NewCreateOrderService().Symbol("ABCBTC").Side(binance.SideTypeBuy).Type(binance.OrderTypeMarket).
+		QuoteOrderQty("0.001").Do(context.Background())
+	assertAPIError(t, err, -1013)
+	assertBalance(t, client, "BTC", "0.009", "0")
+}
+
+func TestOCO(t *testing.T) {
+	s, client := newTestServer(t)
+
+	oco, err := client.NewCreateOCOService().Symbol("XYZBTC").Side(binance.SideTypeSell).Quantity("400").
+		Price("0.00002000").StopPrice("0.00000800").StopLimitPrice("0.00000790").
+		StopLimitTimeInForce(binance.TimeInForceTypeGTC).Do(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// both legs share the locked quantity
+	assertBalance(t, client, "XYZ", "600", "400")
+
+	if err := s.FillOrder(oco.Orders[1].OrderID, "0.00002000"); err != nil {
+		t.Fatal(err)
+	}
+	assertBalance(t, client, "XYZ", "600", "0")
+	assertBalance(t, client, "BTC", "0.018", "0")
+	for _, o := range s.Orders() {
+		if o.OrderID == oco.Orders[0].OrderID && o.Status != binance.OrderStatusTypeExpired {
+			t.Errorf("expected the stop leg to expire, got %v", o.Status)
+		}
+	}
+}
+
+func TestCancelOpenOrders(t *testing.T) {
+	_, client := newTestServer(t)
+
+	for _, price := range []string{"0.00002000", "0.00003000"} {
+		if _, err := limitOrder(client, binance.SideTypeSell, "300", price); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := client.NewCreateOCOService().Symbol("XYZBTC").Side(binance.SideTypeSell).Quantity("400").
+		Price("0.00002000").StopPrice("0.00000800").StopLimitPrice("0.00000790").
+		StopLimitTimeInForce(binance.TimeInForceTypeGTC).Do(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	assertBalance(t, client, "XYZ", "0", "1000")
+
+	if _, err := client.NewCancelOpenOrdersService().Symbol("XYZBTC").Do(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	assertBalance(t, client, "XYZ", "1000", "0")
+}
+
+func TestMyTradesFromID(t *testing.T) {
+	s, client := newTestServer(t)
+	for i := 0; i < 5; i++ {
+		s.AddTrade(binance.TradeV3{Symbol: "XYZBTC", Price: "0.00001", Quantity: "1"})
+	}
+
+	tests := []struct {
+		fromID   int64
+		limit    int
+		expected []int64
+	}{
+		{0, 2, []int64{4, 5}},
+		{1, 2, []int64{1, 2}},
+		{4, 10, []int64{4, 5}},
+	}
+	for _, test := range tests {
+		service := client.NewListTradesService().Symbol("XYZBTC").Limit(test.limit)
+		if test.fromID > 0 {
+			service.FromID(test.fromID)
+		}
+		trades, err := service.Do(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []int64
+		for _, trade := range trades {
+			ids = append(ids, trade.ID)
+		}
+		if len(ids) != len(test.expected) || ids[0] != test.expected[0] || ids[len(ids)-1] != test.expected[len(test.expected)-1] {
+			t.Errorf("trades from %v limit %v: expected %v, got %v", test.fromID, test.limit, test.expected, ids)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package binancefake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/adshao/go-binance/v2"
+	"github.com/gorilla/websocket"
+)
+
+var upgrader = websocket.Upgrader{}
+
+// userStream is the stream name, the user data stream connections are registered with
+const userStream = "user"
+
+// subscriber is a websocket connection to the stream endpoints
+type subscriber struct {
+	conn    *websocket.Conn
+	mutex   sync.Mutex // serializes the writes to the connection
+	streams map[string]bool
+}
+
+func (sub *subscriber) send(v interface{}) error {
+	sub.mutex.Lock()
+	defer sub.mutex.Unlock()
+	return sub.conn.WriteJSON(v)
+}
+
+// CombinedStreamURL returns the base url of the combined websocket streams of this server.
+// The binance client library uses it, if it is assigned to binance.BaseCombinedMainURL.
+func (s *Server) CombinedStreamURL() string {
+	return "ws" + strings.TrimPrefix(s.URL, "http") + "/stream?streams="
+}
+
+// StreamURL returns the base url of the raw websocket streams of this server, used for the user data stream.
+// The binance client library uses it, if it is assigned to binance.BaseWsMainURL.
+func (s *Server) StreamURL() string {
+	return "ws" + strings.TrimPrefix(s.URL, "http") + "/ws"
+}
+
+// handleStream serves the combined websocket streams. Only the trade stream is supported.
+func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
+	s.serveStream(w, r, strings.Split(r.FormValue("streams"), "/"))
+}
+
+// handleUserStream serves the user data stream at /ws/<listenKey>
+func (s *Server) handleUserStream(w http.ResponseWriter, r *http.Request) {
+	s.serveStream(w, r, []string{userStream})
+}
+
+// handleUserDataStream creates, extends and closes listen keys
+func (s *Server) handleUserDataStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, struct{}{})
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.nextListenKey++
+	writeJSON(w, map[string]string{"listenKey": fmt.Sprintf("fake-listen-key-%v", s.nextListenKey)})
+}
+
+// serveStream sends the events of the streams to the websocket client until it disconnects
+func (s *Server) serveStream(w http.ResponseWriter, r *http.Request, streams []string) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	sub := &subscriber{conn: conn, streams: make(map[string]bool)}
+	for _, stream := range streams {
+		sub.streams[stream] = true
+	}
+
+	s.mutex.Lock()
+	s.subscribers[sub] = true
+	s.mutex.Unlock()
+
+	// wait until the client closes the connection
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			break
+		}
+	}
+
+	s.mutex.Lock()
+	delete(s.subscribers, sub)
+	s.mutex.Unlock()
+	conn.Close()
+}
+
+// publishTrade sends a trade event to all subscribers of the symbol.
+// The caller has to hold the mutex.
+func (s *Server) publishTrade(symbol, price string) {
+	stream := strings.ToLower(symbol) + "@trade"
+	s.nextTrade++
+	event := &binance.WsCombinedTradeEvent{
+		Stream: stream,
+		Data: binance.WsTradeEvent{
+			Event:     "trade",
+			Time:      nowMillis(),
+			Symbol:    symbol,
+			TradeID:   s.nextTrade,
+			Price:     price,
+			Quantity:  "1",
+			TradeTime: nowMillis(),
+		},
+	}
+	for sub := range s.subscribers {
+		if sub.streams[stream] {
+			sub.send(event)
+		}
+	}
+}
+
+// publishUserEvent sends the payload together with the event type and time,
+// as flat json object to the user data stream.
+// The caller has to hold the mutex.
+func (s *Server) publishUserEvent(eventType binance.UserDataEventType, payload interface{}) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+	event := make(map[string]interface{})
+	if err := json.Unmarshal(b, &event); err != nil {
+		return
+	}
+	event["e"] = eventType
+	event["E"] = nowMillis()
+	for sub := range s.subscribers {
+		if sub.streams[userStream] {
+			sub.send(event)
+		}
+	}
+}
@@ -22,6 +22,25 @@ type Config struct {
 
 	APIKey    string `config:"" desc:"The API key"`
 	APISecret string `config:"" desc:"The API secret"`
+	StateFile string `config:".trading-shell-state.json" desc:"File to keep the session settings across restarts, empty to disable"`
+	Stream    bool   `config:"true" desc:"Receive live prices over the websocket stream instead of polling"`
+	Currency  string `config:"EUR" desc:"Reporting currency of all values, the invest amount and the limits: EUR, USD, USDT or GBP"`
+	Journal   string `config:".trading-shell-journal.jsonl" desc:"File to append all placed orders and observed fills to, empty to disable. Fills are only observed with the stream on"`
+
+	DryRun          bool `config:"false" desc:"Only print the orders instead of sending them to the exchange"`
+	ConfirmAbove    int  `config:"100" desc:"Ask for confirmation of orders above this value in the reporting currency, 0 to disable"`
+	ConfirmDistance int  `config:"5" desc:"Ask for confirmation of buys more than this percent above or sells below the current price, 0 to disable"`
+
+	RiskMaxOrder      int    `config:"500" desc:"Block orders above this value in the reporting currency, 0 to disable"`
+	RiskMaxDaily      int    `config:"2000" desc:"Block buys, when the value of the buys placed today and the new ones in the reporting currency would exceed it, 0 to disable"`
+	RiskMaxOpenOrders int    `config:"50" desc:"Block orders, when the number of open orders would exceed it, 0 to disable"`
+	RiskMaxDeviation  int    `config:"500" desc:"Block orders with a price more than this percent away from the current price, 0 to disable"`
+	RiskBlacklist     string `config:"" desc:"Comma separated symbols or assets, which can not be traded, e.g. BNB,XYZBTC"`
+
+	Paper       bool   `config:"false" desc:"Simulate all orders in memory instead of placing them on the exchange"`
+	PaperWallet string `config:"BTC:0.01" desc:"The initial wallet for the paper trading, e.g. BTC:0.01,ETH:1"`
+
+	BacktestCache string `config:".trading-shell-klines" desc:"Directory to cache the klines of the backtests, empty to disable"`
 }
 
 func ReadConfig() *Config {
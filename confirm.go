@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/adshao/go-binance/v2"
+)
+
+// orderPreview describes an order, before it is sent to the exchange
+type orderPreview struct {
+	side      binance.SideType
+	orderType binance.OrderType
+	symbol    string
+	qty       F
+	price     F // the limit price or the current price for market orders
+	value     F // the value in the quote asset
+	distance  F // distance of the price from the current price
+	stop      bool
+}
+
+// preview describes an order of the selected symbol
+func (sess *Session) preview(side binance.SideType, orderType binance.OrderType, qty, price F) orderPreview {
+	current := sess.livePrice(sess.selected)
+	return orderPreview{
+		side:      side,
+		orderType: orderType,
+		symbol:    sess.selected,
+		qty:       qty,
+		price:     price,
+		value:     qty.Mult(price),
+		distance:  price.Sub(current).Div(current),
+	}
+}
+
+// wallPreviews describes the placeable limit orders of a wall or ladder
+func (sess *Session) wallPreviews(side binance.SideType, orders []wallOrder) []orderPreview {
+	var previews []orderPreview
+	for _, o := range orders {
+		if o.err == nil {
+			previews = append(previews, sess.preview(side, binance.OrderTypeLimit, o.qty, o.price))
+		}
+	}
+	return previews
+}
+
+// previewValue returns the value of the order in the reporting currency.
+// The orders of other symbols than the selected one are converted at the live prices.
+func (sess *Session) previewValue(p orderPreview) F {
+	if p.symbol == sess.selected {
+		return p.value.Mult(sess.quoteRate)
+	}
+	symbol := sess.allSymbols[p.symbol]
+	if symbol == nil {
+		return FromError(fmt.Errorf("unknown symbol %v", p.symbol))
+	}
+	return p.value.Mult(sess.currencyPrice(symbol.QuoteAsset))
+}
+
+func (sess *Session) formatPreview(p orderPreview) string {
+	quote := ""
+	if symbol := sess.allSymbols[p.symbol]; symbol != nil {
+		quote = symbol.QuoteAsset
+	}
+	return fmt.Sprintf("%v %v %v %v @%v = %v %v / %v, %v from price", p.side, p.orderType, p.qty.StringCompact(), p.symbol, p.price, p.value, quote, sess.formatValue(sess.previewValue(p)), p.distance.FormatPercent())
+}
+
+// exceeds returns the reason, why the order needs a confirmation, or an empty string.
+// Only buys above and sells below the current price are checked for the distance,
+// because they are executed immediately. Stop orders are not executed before their trigger.
+// An order with unknown value or distance always needs a confirmation.
+func (sess *Session) exceeds(p orderPreview) string {
+	value := sess.previewValue(p)
+	if !value.Valid() {
+		return fmt.Sprintf("value unknown: %v", value)
+	}
+	if !p.distance.Valid() {
+		return fmt.Sprintf("distance from the current price unknown: %v", p.distance)
+	}
+	var reasons []string
+	if sess.confirmAbove.Sign() > 0 && value.Cmp(sess.confirmAbove) > 0 {
+		reasons = append(reasons, fmt.Sprintf("value above %v", sess.formatValue(sess.confirmAbove)))
+	}
+	adverse, direction := p.distance, "above"
+	if p.side == binance.SideTypeSell {
+		adverse, direction = adverse.Mult(FromI(-1)), "below"
+	}
+	if !p.stop && sess.confirmDist.Sign() > 0 && adverse.Cmp(sess.confirmDist) > 0 {
+		reasons = append(reasons, fmt.Sprintf("more than %v %v the current price", sess.confirmDist.FormatPercent(), direction))
+	}
+	return strings.Join(reasons, ", ")
+}
+
+// approve decides, if the orders are sent to the exchange.
+// Orders violating the risk limits are blocked. In dry-run mode, the orders are only printed.
+// Orders above the thresholds are shown and have to be confirmed by the user.
+func (sess *Session) approve(previews ...orderPreview) bool {
+	return sess.approveOrders(false, previews)
+}
+
+// approveReplacing approves orders, which replace the open orders of the selected symbol, see approve
+func (sess *Session) approveReplacing(previews ...orderPreview) bool {
+	return sess.approveOrders(true, previews)
+}
+
+func (sess *Session) approveOrders(replacing bool, previews []orderPreview) bool {
+	if err := sess.checkRisk(replacing, previews...); err != nil {
+		sess.Answerf("ORDER BLOCKED BY RISK LIMIT: %v", err)
+		return false
+	}
+	if sess.dryRun {
+		for _, p := range previews {
+			sess.Answerf("DRY RUN: %v", sess.formatPreview(p))
+		}
+		return false
+	}
+	if sess.confirmed {
+		return true
+	}
+
+	needed := false
+	for _, p := range previews {
+		if sess.exceeds(p) != "" {
+			needed = true
+		}
+	}
+	if !needed {
+		return true
+	}
+
+	for _, p := range previews {
+		line := sess.formatPreview(p)
+		if reason := sess.exceeds(p); reason != "" {
+			line += " !!! " + reason
+		}
+		sess.Answer(line)
+	}
+	return sess.confirm()
+}
+
+// confirm asks the user for a confirmation with the next input line.
+// The confirmation is valid for the rest of the command.
+func (sess *Session) confirm() bool {
+	sess.Answer("CONFIRM WITH y, ANYTHING ELSE CANCELS:")
+	if answer := strings.ToLower(strings.TrimSpace(<-sess.in)); answer != "y" && answer != "yes" {
+		sess.Answer("CANCELED, NOTHING SENT")
+		return false
+	}
+	sess.confirmed = true
+	return true
+}
+
+// DryRun shows or switches the dry-run mode, e.g. "on" or "off"
+func (sess *Session) DryRun(arg string) {
+	switch strings.ToLower(arg) {
+	case "on", "true":
+		sess.dryRun = true
+	case "off", "false":
+		sess.dryRun = false
+	case "":
+	default:
+		sess.Answer("USAGE: dry-run [on|off]")
+		return
+	}
+	if sess.dryRun {
+		sess.Answer("dry run is on: orders are only printed")
+	} else {
+		sess.Answer("dry run is off: orders are sent to the exchange")
+	}
+}
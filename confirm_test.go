@@ -0,0 +1,31 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/adshao/go-binance/v2"
+)
+
+// unknownPreviews returns buys with an unknown value and an unknown distance from the current price
+func unknownPreviews() []orderPreview {
+	unknown := FromError(errors.New("no price"))
+	return []orderPreview{
+		{side: binance.SideTypeBuy, orderType: binance.OrderTypeLimit, symbol: "XYZBTC", qty: FromI(100), price: FromS("0.00001"), value: unknown, distance: FromI(0)},
+		{side: binance.SideTypeBuy, orderType: binance.OrderTypeLimit, symbol: "XYZBTC", qty: FromI(100), price: FromS("0.00001"), value: FromS("0.001"), distance: unknown},
+	}
+}
+
+func TestUnknownValuesNeedConfirmation(t *testing.T) {
+	shell := newTestShell(t, nil)
+	shell.run("xyzbtc")
+
+	for i, p := range unknownPreviews() {
+		var reason string
+		shell.task("exceeds", func() { reason = shell.sess.exceeds(p) })
+		if !strings.Contains(reason, "unknown") {
+			t.Errorf("preview %v: expected a confirmation, got %q", i, reason)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/adshao/go-binance/v2"
+)
+
+// currencies maps the supported reporting currencies to the asset, which is used for the conversion.
+// Binance has no USD pairs, so USD is converted as USDT.
+var currencies = map[string]string{
+	"EUR":  "EUR",
+	"USD":  "USDT",
+	"USDT": "USDT",
+	"GBP":  "GBP",
+}
+
+// currencySigns are appended to formatted values, other currencies are appended by name
+var currencySigns = map[string]string{
+	"EUR": "€",
+	"USD": "$",
+	"GBP": "£",
+}
+
+// checkCurrency returns an error, if the reporting currency is not supported
+func checkCurrency(currency string) error {
+	if _, exist := currencies[currency]; !exist {
+		var names []string
+		for name := range currencies {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("currency has to be one of %v: %q", strings.Join(names, ", "), currency)
+	}
+	return nil
+}
+
+// FormatCurrency formats the value with two decimals and the currency, e.g. 12.50€ or 12.50 USDT
+func (f F) FormatCurrency(currency string) string {
+	if !f.Valid() {
+		return fmt.Sprintf("%v", f.Err)
+	}
+	if sign, exist := currencySigns[currency]; exist {
+		return f.V.StringFixed(2) + sign
+	}
+	return f.V.StringFixed(2) + " " + currency
+}
+
+// converter derives the price of one asset in another through the available pairs
+type converter struct {
+	hasSymbol func(symbol string) bool
+	price     func(symbol string) F
+}
+
+// convert returns the price of one unit of the asset in the target asset. It uses a pair of both
+// in either direction, or converts through one of the quote assets, e.g. XYZ -> BTC -> EUR
+func (c converter) convert(asset, target string) F {
+	if asset == target {
+		return FromI(1)
+	}
+	if price, exist := c.pairPrice(asset, target); exist {
+		return price
+	}
+	for _, via := range quoteAssets {
+		if via == asset || via == target {
+			continue
+		}
+		price, exist := c.pairPrice(asset, via)
+		if !exist {
+			continue
+		}
+		if viaTarget, exist := c.pairPrice(via, target); exist {
+			return price.Mult(viaTarget)
+		}
+	}
+	return FromError(fmt.Errorf("no conversion from %v to %v", asset, target))
+}
+
+// pairPrice returns the price of the asset in the quote, from the pair of both
+// in either direction. It returns false, if there is no such pair.
+func (c converter) pairPrice(asset, quote string) (F, bool) {
+	if c.hasSymbol(asset + quote) {
+		return c.price(asset + quote), true
+	}
+	if c.hasSymbol(quote + asset) {
+		return FromI(1).Div(c.price(quote + asset)), true
+	}
+	return F{}, false
+}
+
+// statsConverter converts by one price of the 24h stats, e.g. the last or the open price
+func statsConverter(stats []*binance.PriceChangeStats, price func(s *binance.PriceChangeStats) string) converter {
+	prices := make(map[string]F)
+	for _, s := range stats {
+		prices[s.Symbol] = FromS(price(s))
+	}
+	return converter{
+		hasSymbol: func(symbol string) bool {
+			_, exist := prices[symbol]
+			return exist
+		},
+		price: func(symbol string) F {
+			return prices[symbol]
+		},
+	}
+}
+
+// currencyPrice returns the value of one unit of the asset in the reporting currency
+func (sess *Session) currencyPrice(asset string) F {
+	c := converter{
+		hasSymbol: func(symbol string) bool {
+			return sess.allSymbols[symbol] != nil
+		},
+		price: sess.livePrice,
+	}
+	return c.convert(asset, currencies[sess.currency])
+}
+
+// formatValue formats a value in the reporting currency
+func (sess *Session) formatValue(value F) string {
+	return value.FormatCurrency(sess.currency)
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/adshao/go-binance/v2"
+)
+
+// Exchange is the subset of the exchange api used by the shell.
+type Exchange interface {
+	Prices(symbol string) ([]*binance.SymbolPrice, error)
+	AvgPrice(symbol string) (*binance.AvgPrice, error)
+	PriceChangeStats() ([]*binance.PriceChangeStats, error)
+	ExchangeInfo() (*binance.ExchangeInfo, error)
+	Account() (*binance.Account, error)
+	CreateOrder(order OrderRequest) (*binance.CreateOrderResponse, error)
+	CreateOCO(order OCORequest) (*binance.CreateOCOResponse, error)
+	CancelOpenOrders(symbol string) error
+	// OpenOrders lists the open orders of the symbol, or of all symbols if it is empty
+	OpenOrders(symbol string) ([]*binance.Order, error)
+	Orders(symbol string, limit int) ([]*binance.Order, error)
+	Trades(symbol string, limit int) ([]*binance.TradeV3, error)
+	// TradesFrom lists the trades of the symbol in ascending order, starting with the trade id
+	TradesFrom(symbol string, fromID int64, limit int) ([]*binance.TradeV3, error)
+	// Klines lists the candlesticks of the symbol, starting with the one opened at or after the time in milliseconds
+	Klines(symbol, interval string, startTime int64, limit int) ([]*binance.Kline, error)
+	// TradeStream subscribes to the live trades of the symbols
+	TradeStream(symbols []string, handler binance.WsCombinedTradeHandler, errHandler binance.ErrHandler) (doneC, stopC chan struct{}, err error)
+	// UserDataStream subscribes to the order and balance updates of the account
+	UserDataStream(handler binance.WsUserDataHandler, errHandler binance.ErrHandler) (doneC, stopC chan struct{}, err error)
+}
+
+// OrderRequest describes a new order to be placed on the exchange
+type OrderRequest struct {
+	Symbol        string
+	Side          binance.SideType
+	Type          binance.OrderType
+	TimeInForce   binance.TimeInForceType
+	Quantity      string
+	QuoteOrderQty string // amount of the quote asset to spend or receive by a market order, instead of a quantity
+	Price         string
+	StopPrice     string // trigger price for stop loss and take profit orders
+}
+
+// OCORequest describes a new one-cancels-the-other order list,
+// consisting of a limit order and a stop loss limit order
+type OCORequest struct {
+	Symbol         string
+	Side           binance.SideType
+	Quantity       string
+	Price          string // price of the limit order
+	StopPrice      string // trigger price of the stop loss order
+	StopLimitPrice string // limit price of the stop loss order
+}
+
+// listenKeyKeepalive is the interval to extend the validity of the user data stream, which expires after 60 minutes
+const listenKeyKeepalive = 30 * time.Minute
+
+// BinanceExchange implements the Exchange on top of the binance api client
+type BinanceExchange struct {
+	client *binance.Client
+}
+
+func NewBinanceExchange(client *binance.Client) *BinanceExchange {
+	return &BinanceExchange{
+		client: client,
+	}
+}
+
+func (ex *BinanceExchange) Prices(symbol string) ([]*binance.SymbolPrice, error) {
+	return ex.client.NewListPricesService().Symbol(symbol).Do(context.Background())
+}
+
+func (ex *BinanceExchange) AvgPrice(symbol string) (*binance.AvgPrice, error) {
+	return ex.client.NewAveragePriceService().Symbol(symbol).Do(context.Background())
+}
+
+func (ex *BinanceExchange) PriceChangeStats() ([]*binance.PriceChangeStats, error) {
+	return ex.client.NewListPriceChangeStatsService().Do(context.Background())
+}
+
+func (ex *BinanceExchange) ExchangeInfo() (*binance.ExchangeInfo, error) {
+	return ex.client.NewExchangeInfoService().Do(context.Background())
+}
+
+func (ex *BinanceExchange) Account() (*binance.Account, error) {
+	return ex.client.NewGetAccountService().Do(context.Background())
+}
+
+func (ex *BinanceExchange) CreateOrder(order OrderRequest) (*binance.CreateOrderResponse, error) {
+	service := ex.client.NewCreateOrderService().Symbol(order.Symbol).
+		Side(order.Side).Type(order.Type)
+	if order.Quantity != "" {
+		service.Quantity(order.Quantity)
+	}
+	if order.QuoteOrderQty != "" {
+		service.QuoteOrderQty(order.QuoteOrderQty)
+	}
+	if order.TimeInForce != "" {
+		service.TimeInForce(order.TimeInForce)
+	}
+	if order.Price != "" {
+		service.Price(order.Price)
+	}
+	if order.StopPrice != "" {
+		service.StopPrice(order.StopPrice)
+	}
+	return service.Do(context.Background())
+}
+
+func (ex *BinanceExchange) CreateOCO(order OCORequest) (*binance.CreateOCOResponse, error) {
+	return ex.client.NewCreateOCOService().Symbol(order.Symbol).
+		Side(order.Side).Quantity(order.Quantity).
+		Price(order.Price).StopPrice(order.StopPrice).
+		StopLimitPrice(order.StopLimitPrice).StopLimitTimeInForce(binance.TimeInForceTypeGTC).
+		Do(context.Background())
+}
+
+func (ex *BinanceExchange) CancelOpenOrders(symbol string) error {
+	_, err := ex.client.NewCancelOpenOrdersService().Symbol(symbol).Do(context.Background())
+	return err
+}
+
+func (ex *BinanceExchange) OpenOrders(symbol string) ([]*binance.Order, error) {
+	return ex.client.NewListOpenOrdersService().Symbol(symbol).Do(context.Background())
+}
+
+func (ex *BinanceExchange) Orders(symbol string, limit int) ([]*binance.Order, error) {
+	return ex.client.NewListOrdersService().Symbol(symbol).Limit(limit).Do(context.Background())
+}
+
+func (ex *BinanceExchange) Trades(symbol string, limit int) ([]*binance.TradeV3, error) {
+	return ex.client.NewListTradesService().Symbol(symbol).Limit(limit).Do(context.Background())
+}
+
+func (ex *BinanceExchange) TradesFrom(symbol string, fromID int64, limit int) ([]*binance.TradeV3, error) {
+	return ex.client.NewListTradesService().Symbol(symbol).FromID(fromID).Limit(limit).Do(context.Background())
+}
+
+func (ex *BinanceExchange) Klines(symbol, interval string, startTime int64, limit int) ([]*binance.Kline, error) {
+	return ex.client.NewKlinesService().Symbol(symbol).Interval(interval).StartTime(startTime).Limit(limit).Do(context.Background())
+}
+
+func (ex *BinanceExchange) TradeStream(symbols []string, handler binance.WsCombinedTradeHandler, errHandler binance.ErrHandler) (doneC, stopC chan struct{}, err error) {
+	return binance.WsCombinedTradeServe(symbols, handler, errHandler)
+}
+
+// UserDataStream opens the user data stream and keeps its listen key alive, until the stream is closed
+func (ex *BinanceExchange) UserDataStream(handler binance.WsUserDataHandler, errHandler binance.ErrHandler) (doneC, stopC chan struct{}, err error) {
+	listenKey, err := ex.client.NewStartUserStreamService().Do(context.Background())
+	if err != nil {
+		return nil, nil, err
+	}
+	doneC, stopC, err = binance.WsUserDataServe(listenKey, handler, errHandler)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	go func() {
+		ticker := time.NewTicker(listenKeyKeepalive)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-doneC:
+				ex.client.NewCloseUserStreamService().ListenKey(listenKey).Do(context.Background())
+				return
+			case <-ticker.C:
+				if err := ex.client.NewKeepaliveUserStreamService().ListenKey(listenKey).Do(context.Background()); err != nil {
+					errHandler(err)
+				}
+			}
+		}
+	}()
+	return doneC, stopC, nil
+}
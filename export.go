@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/adshao/go-binance/v2"
+)
+
+// exportDateFormat is the format of the date range of the export
+const exportDateFormat = "2006-01-02"
+
+// klineBlock is the number of minute klines, which are fetched by one request
+const klineBlock = 1000
+
+// historicPrices looks up the close prices of minute klines, cached by symbol and minute.
+// The klines are fetched in blocks, so the trades of a day need one or two requests per symbol.
+type historicPrices struct {
+	exchange Exchange
+	cache    map[string]F
+	blocks   map[string]error // the fetched blocks by symbol and start, with the error of the request
+}
+
+func newHistoricPrices(exchange Exchange) *historicPrices {
+	return &historicPrices{
+		exchange: exchange,
+		cache:    make(map[string]F),
+		blocks:   make(map[string]error),
+	}
+}
+
+// price returns the close price of the symbol in the minute of the time in milliseconds
+func (h *historicPrices) price(symbol string, millis int64) F {
+	minute := millis - millis%time.Minute.Milliseconds()
+	key := fmt.Sprintf("%v@%v", symbol, minute)
+	if price, exist := h.cache[key]; exist {
+		return price
+	}
+
+	start := minute - minute%(klineBlock*time.Minute.Milliseconds())
+	blockKey := fmt.Sprintf("%v@%v", symbol, start)
+	err, fetched := h.blocks[blockKey]
+	if !fetched {
+		var klines []*binance.Kline
+		klines, err = h.exchange.Klines(symbol, "1m", start, klineBlock)
+		for _, k := range klines {
+			h.cache[fmt.Sprintf("%v@%v", symbol, k.OpenTime)] = FromS(k.Close)
+		}
+		h.blocks[blockKey] = err
+	}
+	if err != nil {
+		return FromError(fmt.Errorf("could not fetch %v klines: %w", symbol, err))
+	}
+	if price, exist := h.cache[key]; exist {
+		return price
+	}
+	return FromError(fmt.Errorf("no %v price at %v", symbol, time.Unix(0, minute*int64(time.Millisecond)).UTC()))
+}
+
+// converter returns a converter of the prices at the time in milliseconds
+func (h *historicPrices) converter(symbols map[string]*binance.Symbol, millis int64) converter {
+	return converter{
+		hasSymbol: func(symbol string) bool {
+			return symbols[symbol] != nil
+		},
+		price: func(symbol string) F {
+			return h.price(symbol, millis)
+		},
+	}
+}
+
+// ParseExportArgs parses the arguments of the export: comma separated symbols and the date range,
+// e.g. "XYZBTC,ABCUSDT 2021-01-01 2021-12-31". The end date is included.
+func ParseExportArgs(args []string) (symbols []string, from, to time.Time, err error) {
+	if len(args) != 3 {
+		return nil, from, to, fmt.Errorf("USAGE: export-trades <symbol,symbol,..> <from yyyy-mm-dd> <to yyyy-mm-dd>")
+	}
+	for _, s := range strings.Split(args[0], ",") {
+		if s = strings.ToUpper(strings.TrimSpace(s)); s != "" {
+			symbols = append(symbols, s)
+		}
+	}
+	if from, err = time.ParseInLocation(exportDateFormat, args[1], time.Local); err != nil {
+		return nil, from, to, fmt.Errorf("invalid date: %q", args[1])
+	}
+	if to, err = time.ParseInLocation(exportDateFormat, args[2], time.Local); err != nil {
+		return nil, from, to, fmt.Errorf("invalid date: %q", args[2])
+	}
+	return symbols, from, to.AddDate(0, 0, 1), nil
+}
+
+// ExportTrades writes the trades of the symbols in the time range as csv. All values are converted
+// to the currency with the prices at the trade time. The gains of the sells are computed
+// first in first out over the complete trade history of the base asset in all exported symbols,
+// so buys before the range and in other quote assets are included.
+// A trade before the range without a price is not valued: its buy is not added to the lots,
+// so the later sell of it is unmatched.
+func ExportTrades(exchange Exchange, currency string, symbols []string, from, to time.Time, w io.Writer) error {
+	if err := checkCurrency(currency); err != nil {
+		return err
+	}
+	info, err := exchange.ExchangeInfo()
+	if err != nil {
+		return err
+	}
+	allSymbols := make(map[string]*binance.Symbol)
+	for i := range info.Symbols {
+		allSymbols[info.Symbols[i].Symbol] = &info.Symbols[i]
+	}
+
+	var trades []*binance.TradeV3
+	for _, symbol := range symbols {
+		if allSymbols[symbol] == nil {
+			return fmt.Errorf("symbol not found: %q", symbol)
+		}
+		symbolTrades, err := AllTrades(exchange, symbol, 0)
+		if err != nil {
+			return fmt.Errorf("could not fetch the trades of %v: %w", symbol, err)
+		}
+		trades = append(trades, symbolTrades...)
+	}
+	sort.SliceStable(trades, func(i, j int) bool {
+		return trades[i].Time < trades[j].Time
+	})
+
+	out := csv.NewWriter(w)
+	out.Write([]string{"time", "symbol", "side", "qty", "price", "quote",
+		"value " + currency, "fee", "fee asset", "fee " + currency, "cost " + currency, "gain " + currency, "unmatched qty"})
+
+	prices := newHistoricPrices(exchange)
+	lots := make(map[string]*fifoLots) // by base asset
+	for _, t := range trades {
+		s := allSymbols[t.Symbol]
+		if lots[s.BaseAsset] == nil {
+			lots[s.BaseAsset] = &fifoLots{}
+		}
+		tradeTime := time.Unix(0, t.Time*int64(time.Millisecond))
+		inRange := !tradeTime.Before(from) && tradeTime.Before(to)
+
+		c := prices.converter(allSymbols, t.Time)
+		qty := FromS(t.Quantity)
+		fee := FromS(t.Commission)
+		value := FromS(t.QuoteQuantity).Mult(c.convert(s.QuoteAsset, currencies[currency]))
+		feeValue := FromI(0)
+		switch {
+		case fee.Sign() <= 0:
+		case t.CommissionAsset == s.BaseAsset:
+			// valued with the price of the trade
+			feeValue = fee.Mult(value).Div(qty)
+		default:
+			feeValue = fee.Mult(c.convert(t.CommissionAsset, currencies[currency]))
+		}
+		valid := value.Valid() && feeValue.Valid()
+		if !valid && inRange {
+			return fmt.Errorf("no %v value of %v trade %v: %v %v", currency, t.Symbol, t.ID, value, feeValue)
+		}
+
+		// a buy without value is not added to the lots
+		var cost, gain, unmatched F
+		if valid || !t.IsBuyer {
+			cost, gain, unmatched = lots[s.BaseAsset].trade(t, s.BaseAsset, value, feeValue)
+		}
+
+		if !inRange {
+			continue
+		}
+		row := []string{tradeTime.Local().Format(time.RFC3339), t.Symbol, sideOf(t), FromS(t.Quantity).StringCompact(), t.Price, s.QuoteAsset,
+			value.V.StringFixed(2), fee.StringCompact(), t.CommissionAsset, feeValue.V.StringFixed(2), "", "", ""}
+		if !t.IsBuyer {
+			row[10], row[11], row[12] = cost.V.StringFixed(2), gain.V.StringFixed(2), unmatched.StringCompact()
+		}
+		out.Write(row)
+	}
+	out.Flush()
+	return out.Error()
+}
+
+func sideOf(t *binance.TradeV3) string {
+	if t.IsBuyer {
+		return string(binance.SideTypeBuy)
+	}
+	return string(binance.SideTypeSell)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/adshao/go-binance/v2"
+	"github.com/smancke/trading-shell/binancefake"
+)
+
+// klineCounter counts the kline requests of the exchange
+type klineCounter struct {
+	Exchange
+	requests int
+}
+
+func (ex *klineCounter) Klines(symbol, interval string, startTime int64, limit int) ([]*binance.Kline, error) {
+	ex.requests++
+	return ex.Exchange.Klines(symbol, interval, startTime, limit)
+}
+
+func TestExportTrades(t *testing.T) {
+	block := klineBlock * time.Minute.Milliseconds()
+	start := 1600000000000 - 1600000000000%block
+	minute := time.Minute.Milliseconds()
+
+	srv := binancefake.NewServer()
+	t.Cleanup(srv.Close)
+	srv.AddSymbol("XYZBTC", "XYZ", "BTC")
+	srv.AddSymbol("XYZUSDT", "XYZ", "USDT")
+	srv.AddSymbol("BTCEUR", "BTC", "EUR")
+	srv.AddSymbol("EURUSDT", "EUR", "USDT")
+	srv.SetKlines("BTCEUR", []binance.Kline{
+		{OpenTime: start + 2*block, Close: "50000"},
+		{OpenTime: start + 4*block + 10*minute, Close: "50000"},
+	})
+	srv.SetKlines("EURUSDT", []binance.Kline{{OpenTime: start + 4*block + 5*minute, Close: "1.25"}})
+
+	// a buy without price before the range, a buy before the range and sells of both symbols in the range
+	srv.AddTrade(binance.TradeV3{Symbol: "XYZBTC", Time: start, IsBuyer: true, Quantity: "10", QuoteQuantity: "0.0001", Price: "0.00001"})
+	srv.AddTrade(binance.TradeV3{Symbol: "XYZBTC", Time: start + 2*block, IsBuyer: true, Quantity: "100", QuoteQuantity: "0.001", Price: "0.00001"})
+	srv.AddTrade(binance.TradeV3{Symbol: "XYZUSDT", Time: start + 4*block + 5*minute, Quantity: "50", QuoteQuantity: "40", Price: "0.8"})
+	srv.AddTrade(binance.TradeV3{Symbol: "XYZBTC", Time: start + 4*block + 10*minute, Quantity: "60", QuoteQuantity: "0.0006", Price: "0.00001"})
+
+	exchange := &klineCounter{Exchange: NewBinanceExchange(srv.BinanceClient())}
+	from := time.Unix(0, (start+3*block)*int64(time.Millisecond))
+	var out bytes.Buffer
+	if err := ExportTrades(exchange, "EUR", []string{"XYZBTC", "XYZUSDT"}, from, from.Add(time.Hour*24), &out); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the lots of XYZ are shared by both symbols
+	expected := [][]string{
+		{"XYZUSDT", "SELL", "32.00", "25.00", "7.00", "0"},
+		{"XYZBTC", "SELL", "30.00", "25.00", "0.00", "10"},
+	}
+	if len(rows) != len(expected)+1 {
+		t.Fatalf("expected %v trades, got %v", len(expected), rows)
+	}
+	for i, e := range expected {
+		row := rows[i+1]
+		if got := []string{row[1], row[2], row[6], row[10], row[11], row[12]}; !equalStrings(got, e) {
+			t.Errorf("trade %v: expected %v, got %v", i+1, e, got)
+		}
+	}
+
+	// one request per block and symbol: BTCEUR for the blocks at start, + 2 and + 4, EURUSDT for + 4
+	if exchange.requests != 4 {
+		t.Errorf("expected 4 kline requests, got %v", exchange.requests)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/adshao/go-binance/v2"
+)
+
+// SymbolFilters are the trading rules of a symbol, as defined in the exchange info.
+// Zero values mean, that there is no restriction.
+type SymbolFilters struct {
+	MinQty      F
+	MaxQty      F
+	StepSize    F
+	MinPrice    F
+	MaxPrice    F
+	TickSize    F
+	MinNotional F
+}
+
+// defaultFilters are used, if no exchange info is available for a symbol
+var defaultFilters = SymbolFilters{
+	StepSize: FromI(1),
+	TickSize: minPrecision,
+}
+
+// minPrecision is the smallest unit of quantities and prices on the exchange
+var minPrecision = FromS("0.00000001")
+
+func NewSymbolFilters(symbol *binance.Symbol) SymbolFilters {
+	if symbol == nil {
+		return defaultFilters
+	}
+	filters := SymbolFilters{}
+	for _, filter := range symbol.Filters {
+		switch filter["filterType"] {
+		case "LOT_SIZE":
+			filters.MinQty = filterValue(filter, "minQty")
+			filters.MaxQty = filterValue(filter, "maxQty")
+			filters.StepSize = filterValue(filter, "stepSize")
+		case "PRICE_FILTER":
+			filters.MinPrice = filterValue(filter, "minPrice")
+			filters.MaxPrice = filterValue(filter, "maxPrice")
+			filters.TickSize = filterValue(filter, "tickSize")
+		case "MIN_NOTIONAL", "NOTIONAL":
+			filters.MinNotional = filterValue(filter, "minNotional")
+		}
+	}
+	if filters.StepSize.Sign() == 0 {
+		filters.StepSize = minPrecision
+	}
+	if filters.TickSize.Sign() == 0 {
+		filters.TickSize = minPrecision
+	}
+	return filters
+}
+
+func filterValue(filter map[string]interface{}, key string) F {
+	if s, ok := filter[key].(string); ok {
+		if f := FromS(s); f.Valid() {
+			return f
+		}
+	}
+	return F{}
+}
+
+// Quantity rounds the quantity down to the step size
+func (filters SymbolFilters) Quantity(qty F) F {
+	return qty.FloorTo(filters.StepSize)
+}
+
+// Price rounds the price to the tick size.
+// Buy prices are rounded down and sell prices are rounded up, so that the limit is never worse than requested.
+func (filters SymbolFilters) Price(price F, side binance.SideType) F {
+	if side == binance.SideTypeSell {
+		return price.CeilTo(filters.TickSize)
+	}
+	return price.FloorTo(filters.TickSize)
+}
+
+// Check returns an error, if an order with quantity and price violates the filters
+func (filters SymbolFilters) Check(qty, price F) error {
+	if !qty.Valid() {
+		return qty.Err
+	}
+	if !price.Valid() {
+		return price.Err
+	}
+	if qty.Sign() <= 0 {
+		return fmt.Errorf("quantity %v is not positive", qty.StringCompact())
+	}
+	if qty.Cmp(filters.MinQty) < 0 {
+		return fmt.Errorf("quantity %v is below the minimum quantity of %v", qty.StringCompact(), filters.MinQty.StringCompact())
+	}
+	if filters.MaxQty.Sign() > 0 && qty.Cmp(filters.MaxQty) > 0 {
+		return fmt.Errorf("quantity %v is above the maximum quantity of %v", qty.StringCompact(), filters.MaxQty.StringCompact())
+	}
+	if price.Cmp(filters.MinPrice) < 0 {
+		return fmt.Errorf("price %v is below the minimum price of %v", price, filters.MinPrice)
+	}
+	if filters.MaxPrice.Sign() > 0 && price.Cmp(filters.MaxPrice) > 0 {
+		return fmt.Errorf("price %v is above the maximum price of %v", price, filters.MaxPrice)
+	}
+	if notional := qty.Mult(price); notional.Cmp(filters.MinNotional) < 0 {
+		return fmt.Errorf("order value %v is below the minimum of %v", notional, filters.MinNotional)
+	}
+	return nil
+}
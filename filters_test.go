@@ -0,0 +1,104 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/adshao/go-binance/v2"
+)
+
+func testFilters() SymbolFilters {
+	return NewSymbolFilters(&binance.Symbol{
+		Symbol: "XYZBTC",
+		Filters: []map[string]interface{}{
+			{"filterType": "LOT_SIZE", "minQty": "0.10000000", "maxQty": "1000.00000000", "stepSize": "0.10000000"},
+			{"filterType": "PRICE_FILTER", "minPrice": "0.00000100", "maxPrice": "1.00000000", "tickSize": "0.00000100"},
+			{"filterType": "MIN_NOTIONAL", "minNotional": "0.00010000"},
+		},
+	})
+}
+
+func TestNewSymbolFilters(t *testing.T) {
+	filters := testFilters()
+	tests := []struct {
+		name     string
+		value    F
+		expected string
+	}{
+		{"MinQty", filters.MinQty, "0.1"},
+		{"MaxQty", filters.MaxQty, "1000"},
+		{"StepSize", filters.StepSize, "0.1"},
+		{"MinPrice", filters.MinPrice, "0.000001"},
+		{"MaxPrice", filters.MaxPrice, "1"},
+		{"TickSize", filters.TickSize, "0.000001"},
+		{"MinNotional", filters.MinNotional, "0.0001"},
+	}
+	for _, test := range tests {
+		if test.value.Cmp(FromS(test.expected)) != 0 {
+			t.Errorf("%v: expected %v, got %v", test.name, test.expected, test.value)
+		}
+	}
+
+	// without filters, only the precision of the exchange applies
+	empty := NewSymbolFilters(&binance.Symbol{Symbol: "XYZBTC"})
+	if empty.StepSize.Cmp(minPrecision) != 0 || empty.TickSize.Cmp(minPrecision) != 0 {
+		t.Errorf("expected the min precision as step and tick size, got %+v", empty)
+	}
+	if NewSymbolFilters(nil).StepSize.Cmp(FromI(1)) != 0 {
+		t.Errorf("expected the default filters without symbol")
+	}
+}
+
+func TestFiltersRounding(t *testing.T) {
+	filters := testFilters()
+	tests := []struct {
+		value     string
+		qty       string
+		buyPrice  string
+		sellPrice string
+	}{
+		{"12.34567", "12.3", "12.345670", "12.345670"},
+		{"0.0000123456", "0", "0.000012", "0.000013"},
+		{"0.000012", "0", "0.000012", "0.000012"},
+		{"100", "100", "100", "100"},
+	}
+	for _, test := range tests {
+		value := FromS(test.value)
+		if qty := filters.Quantity(value); qty.Cmp(FromS(test.qty)) != 0 {
+			t.Errorf("quantity of %v: expected %v, got %v", test.value, test.qty, qty)
+		}
+		if price := filters.Price(value, binance.SideTypeBuy); price.Cmp(FromS(test.buyPrice)) != 0 {
+			t.Errorf("buy price of %v: expected %v, got %v", test.value, test.buyPrice, price)
+		}
+		if price := filters.Price(value, binance.SideTypeSell); price.Cmp(FromS(test.sellPrice)) != 0 {
+			t.Errorf("sell price of %v: expected %v, got %v", test.value, test.sellPrice, price)
+		}
+	}
+}
+
+func TestFiltersCheck(t *testing.T) {
+	filters := testFilters()
+	tests := []struct {
+		qty   F
+		price F
+		err   string
+	}{
+		{FromS("10"), FromS("0.00001"), ""},
+		{FromS("0"), FromS("0.00001"), "not positive"},
+		{FromS("0.05"), FromS("0.1"), "below the minimum quantity"},
+		{FromS("1000.1"), FromS("0.00001"), "above the maximum quantity"},
+		{FromS("100"), FromS("0.0000005"), "below the minimum price"},
+		{FromS("0.1"), FromS("1.1"), "above the maximum price"},
+		{FromS("5"), FromS("0.00001"), "order value"},
+		{FromS("x"), FromS("0.00001"), "can't convert"},
+	}
+	for _, test := range tests {
+		err := filters.Check(test.qty, test.price)
+		if test.err == "" && err != nil {
+			t.Errorf("%v @%v: unexpected error %v", test.qty, test.price, err)
+		}
+		if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+			t.Errorf("%v @%v: expected error containing %q, got %v", test.qty, test.price, test.err, err)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adshao/go-binance/v2"
+)
+
+const (
+	journalEventOrder = "order" // an order was placed by the shell
+	journalEventFill  = "fill"  // an order was executed, observed on the user data stream
+
+	// journalDateFormat is the format of the date range of the journal command
+	journalDateFormat = "2006-01-02"
+
+	// maxJournalLines is the number of entries shown by the journal command
+	maxJournalLines = 50
+)
+
+// journalEntry is one line of the journal, together with the session parameters at the time.
+// For fills, the command and parameters are the ones of the placed order.
+type journalEntry struct {
+	Time        time.Time `json:"time"`
+	Event       string    `json:"event"`
+	Command     string    `json:"command,omitempty"`
+	Symbol      string    `json:"symbol"`
+	Side        string    `json:"side"`
+	Type        string    `json:"type,omitempty"`
+	OrderID     int64     `json:"orderId,omitempty"`
+	Qty         string    `json:"qty"`
+	Price       string    `json:"price,omitempty"`
+	StopPrice   string    `json:"stopPrice,omitempty"`
+	Status      string    `json:"status,omitempty"`
+	BasePrice   string    `json:"basePrice,omitempty"`
+	BuyMaxMult  string    `json:"buyMaxMult,omitempty"`
+	SellMaxMult string    `json:"sellMaxMult,omitempty"`
+	SellMinMult string    `json:"sellMinMult,omitempty"`
+}
+
+func (e journalEntry) String() string {
+	line := fmt.Sprintf("%v %-5v %v %v %v %v", e.Time.Local().Format("2006-01-02 15:04:05"), e.Event, e.Side, e.Type, FromS(e.Qty).StringCompact(), e.Symbol)
+	if e.Price != "" {
+		line += " @" + e.Price
+	}
+	if e.StopPrice != "" {
+		line += " trigger@" + e.StopPrice
+	}
+	if e.Status != "" {
+		line += " " + e.Status
+	}
+	if e.BasePrice != "" {
+		line += " base " + e.BasePrice
+	}
+	if e.Command != "" {
+		line += fmt.Sprintf(" (%v)", e.Command)
+	}
+	return line
+}
+
+// journal appends the placed orders and observed fills as json lines to a file.
+// It is safe to be used from background routines.
+type journal struct {
+	mutex  sync.Mutex
+	file   string
+	orders map[int64]journalEntry // the orders placed in this session, by id
+}
+
+func newJournal(file string) *journal {
+	return &journal{
+		file:   file,
+		orders: make(map[int64]journalEntry),
+	}
+}
+
+// record appends the entry to the journal file
+func (j *journal) record(entry journalEntry) error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	if entry.Event == journalEventOrder && entry.OrderID != 0 {
+		j.orders[entry.OrderID] = entry
+	}
+	if j.file == "" {
+		return nil
+	}
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(j.file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(b, '\n'))
+	return err
+}
+
+// fill records the execution of an order with the context of the placed order
+func (j *journal) fill(o *binance.WsOrderUpdate) error {
+	entry := journalEntry{
+		Time:    time.Now(),
+		Event:   journalEventFill,
+		Symbol:  o.Symbol,
+		Side:    o.Side,
+		Type:    o.Type,
+		OrderID: o.Id,
+		Qty:     o.LatestVolume,
+		Price:   o.LatestPrice,
+		Status:  o.Status,
+	}
+	if order, exist := j.order(o.Id); exist {
+		entry.Command = order.Command
+		entry.BasePrice = order.BasePrice
+		entry.BuyMaxMult = order.BuyMaxMult
+		entry.SellMaxMult = order.SellMaxMult
+		entry.SellMinMult = order.SellMinMult
+	}
+	return j.record(entry)
+}
+
+// order returns the entry of a placed order. Orders of former sessions are looked up in the file.
+func (j *journal) order(id int64) (journalEntry, bool) {
+	j.mutex.Lock()
+	entry, exist := j.orders[id]
+	j.mutex.Unlock()
+	if exist {
+		return entry, true
+	}
+
+	entries, err := j.read(func(e journalEntry) bool {
+		return e.Event == journalEventOrder && e.OrderID == id
+	})
+	if err != nil || len(entries) == 0 {
+		return journalEntry{}, false
+	}
+	return entries[len(entries)-1], true
+}
+
+// read returns the entries of the file, which match the filter
+func (j *journal) read(match func(journalEntry) bool) ([]journalEntry, error) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	if j.file == "" {
+		return nil, fmt.Errorf("no journal file configured")
+	}
+	f, err := os.Open(j.file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []journalEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		if match(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// journalOrder records a placed order with the current command and session parameters
+func (sess *Session) journalOrder(symbol string, side binance.SideType, orderType binance.OrderType, orderID int64, qty, price, stop string, status binance.OrderStatusType) {
+	entry := journalEntry{
+		Time:        time.Now(),
+		Event:       journalEventOrder,
+		Command:     sess.command,
+		Symbol:      symbol,
+		Side:        string(side),
+		Type:        string(orderType),
+		OrderID:     orderID,
+		Qty:         qty,
+		Price:       price,
+		Status:      string(status),
+		BuyMaxMult:  sess.buyMaxMult.StringCompact(),
+		SellMaxMult: sess.sellMaxMult.StringCompact(),
+		SellMinMult: sess.sellMinMult.StringCompact(),
+	}
+	if stop := FromS(stop); stop.Valid() && stop.Sign() > 0 {
+		entry.StopPrice = stop.String()
+	}
+	if symbol == sess.selected && sess.basePrice.Valid() {
+		entry.BasePrice = sess.basePrice.String()
+	}
+	if err := sess.journal.record(entry); err != nil {
+		sess.Answerf("ERROR ON WRITING JOURNAL: %v", err)
+	}
+}
+
+// journalMarketOrder records an executed market order with its average price
+func (sess *Session) journalMarketOrder(order *binance.CreateOrderResponse) {
+	price := ""
+	if executed := FromS(order.ExecutedQuantity); executed.Valid() && executed.Sign() > 0 {
+		price = FromS(order.CummulativeQuoteQuantity).Div(executed).String()
+	}
+	sess.journalOrder(order.Symbol, order.Side, order.Type, order.OrderID, order.ExecutedQuantity, price, "", order.Status)
+}
+
+// journalArgs parses the arguments of the journal command: an optional symbol or "all",
+// followed by an optional date range, e.g. "XYZBTC 2021-05-01 2021-05-31"
+func (sess *Session) journalArgs(arg string) (symbol string, from, to time.Time, err error) {
+	symbol = sess.selected
+	args := strings.Fields(arg)
+	if len(args) > 0 {
+		if _, err := time.Parse(journalDateFormat, args[0]); err != nil {
+			symbol = strings.ToUpper(args[0])
+			args = args[1:]
+		}
+	}
+	if symbol == "ALL" {
+		symbol = ""
+	}
+	if len(args) > 2 {
+		return symbol, from, to, fmt.Errorf("too many arguments")
+	}
+	if len(args) > 0 {
+		if from, err = time.ParseInLocation(journalDateFormat, args[0], time.Local); err != nil {
+			return symbol, from, to, fmt.Errorf("invalid date: %q", args[0])
+		}
+	}
+	if len(args) > 1 {
+		if to, err = time.ParseInLocation(journalDateFormat, args[1], time.Local); err != nil {
+			return symbol, from, to, fmt.Errorf("invalid date: %q", args[1])
+		}
+		// the end date is included
+		to = to.AddDate(0, 0, 1)
+	}
+	return symbol, from, to, nil
+}
+
+// Journal prints the recorded orders and fills of a symbol and date range.
+// Without symbol, the selected symbol is used, e.g. "journal all 2021-05-01 2021-05-31".
+// Fills are observed on the user data stream, so they are missing, if the stream is off.
+func (sess *Session) Journal(arg string) {
+	symbol, from, to, err := sess.journalArgs(arg)
+	if err != nil {
+		sess.Answerf("USAGE: journal [symbol|all] [from yyyy-mm-dd] [to yyyy-mm-dd]: %v", err)
+		return
+	}
+
+	entries, err := sess.journal.read(func(e journalEntry) bool {
+		return (symbol == "" || e.Symbol == symbol) &&
+			(from.IsZero() || !e.Time.Before(from)) &&
+			(to.IsZero() || e.Time.Before(to))
+	})
+	if err != nil {
+		sess.Answerf("ERROR ON READING JOURNAL: %v", err)
+		return
+	}
+	if len(entries) > maxJournalLines {
+		sess.Answerf("(%v older entries not shown)", len(entries)-maxJournalLines)
+		entries = entries[len(entries)-maxJournalLines:]
+	}
+	for _, e := range entries {
+		sess.Answer(e.String())
+	}
+	if len(entries) == 0 {
+		sess.Answer("no journal entries")
+	}
+	if !sess.streaming {
+		sess.Answer("(the stream is off, fills are not journaled)")
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adshao/go-binance/v2"
+	"github.com/smancke/trading-shell/binancefake"
+	"github.com/smancke/trading-shell/config"
+)
+
+func TestJournalFill(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "journal.jsonl")
+	j := newJournal(file)
+	order := journalEntry{Time: time.Now(), Event: journalEventOrder, Command: "buy 1", Symbol: "XYZBTC", Side: "BUY", OrderID: 7, BasePrice: "0.00001000"}
+	if err := j.record(order); err != nil {
+		t.Fatal(err)
+	}
+	fill := &binance.WsOrderUpdate{Symbol: "XYZBTC", Side: "BUY", Type: "LIMIT", Id: 7, LatestVolume: "100", LatestPrice: "0.00001", Status: "FILLED"}
+	if err := j.fill(fill); err != nil {
+		t.Fatal(err)
+	}
+
+	// the order of a former session is looked up in the file
+	if err := newJournal(file).fill(fill); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := j.read(func(e journalEntry) bool { return e.Event == journalEventFill })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 fills, got %+v", entries)
+	}
+	for _, e := range entries {
+		if e.Command != "buy 1" || e.BasePrice != "0.00001000" || e.Qty != "100" || e.Status != "FILLED" {
+			t.Errorf("expected the fill with the context of the order, got %+v", e)
+		}
+	}
+}
+
+func TestJournalArgs(t *testing.T) {
+	sess := &Session{selected: "XYZBTC"}
+	day := func(value string) time.Time {
+		d, _ := time.ParseInLocation(journalDateFormat, value, time.Local)
+		return d
+	}
+	tests := []struct {
+		arg    string
+		symbol string
+		from   time.Time
+		to     time.Time
+		err    bool
+	}{
+		{"", "XYZBTC", time.Time{}, time.Time{}, false},
+		{"all", "", time.Time{}, time.Time{}, false},
+		{"abcbtc 2021-05-01", "ABCBTC", day("2021-05-01"), time.Time{}, false},
+		{"2021-05-01 2021-05-31", "XYZBTC", day("2021-05-01"), day("2021-06-01"), false},
+		{"all 2021-05-01 2021-05-31 2021-06-01", "", time.Time{}, time.Time{}, true},
+		{"xyzbtc 2021-05-32", "", time.Time{}, time.Time{}, true},
+	}
+	for _, test := range tests {
+		symbol, from, to, err := sess.journalArgs(test.arg)
+		if test.err {
+			if err == nil {
+				t.Errorf("%q: expected an error", test.arg)
+			}
+			continue
+		}
+		if err != nil || symbol != test.symbol || !from.Equal(test.from) || !to.Equal(test.to) {
+			t.Errorf("%q: expected %v %v %v, got %v %v %v (%v)", test.arg, test.symbol, test.from, test.to, symbol, from, to, err)
+		}
+	}
+}
+
+func TestJournalCommand(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "journal.jsonl")
+	shell := newTestShell(t, func(srv *binancefake.Server, c *config.Config) {
+		srv.SetBalance("XYZ", "100", "0")
+		c.Journal = file
+		c.Stream = false
+	})
+	if out := shell.run("journal all"); !strings.Contains(out, "no journal entries") || !strings.Contains(out, "fills are not journaled") {
+		t.Errorf("expected an empty journal and the note about the stream, got:\n%v", out)
+	}
+
+	shell.run("xyzbtc")
+	shell.run("sell-wall 2")
+	out := shell.run("journal")
+	if strings.Count(out, "order SELL LIMIT") != 4 || strings.Count(out, "(sell-wall 2)") != 4 || !strings.Contains(out, "base 0.00001000") {
+		t.Errorf("expected the 4 orders of the sell wall with their command, got:\n%v", out)
+	}
+	if out := shell.run("journal abcbtc"); !strings.Contains(out, "no journal entries") {
+		t.Errorf("expected no entries of another symbol, got:\n%v", out)
+	}
+}
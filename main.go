@@ -21,7 +21,18 @@ func main() {
 
 	if len(os.Args) > 1 {
 		if os.Args[1] == "list-push-coins" {
-			PrintPushCoins(binance.NewClient(config.APIKey, config.APISecret))
+			PrintPushCoins(NewBinanceExchange(binance.NewClient(config.APIKey, config.APISecret)), strings.ToUpper(config.Currency))
+			return
+		}
+		if os.Args[1] == "export-trades" {
+			symbols, from, to, err := ParseExportArgs(os.Args[2:])
+			if err == nil {
+				err = ExportTrades(NewBinanceExchange(binance.NewClient(config.APIKey, config.APISecret)), strings.ToUpper(config.Currency), symbols, from, to, os.Stdout)
+			}
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(nil, err)
+			}
 			return
 		}
 	}
@@ -38,7 +49,12 @@ func main() {
 		return
 	}
 
-	app.startConsole()
+	err = app.startConsole()
+	if err != nil {
+		fmt.Println(err)
+		exit(nil, err)
+		return
+	}
 
 	<-stop
 	app.stop()
@@ -63,8 +79,26 @@ type application struct {
 	httpSrv *http.Server
 }
 
-func (app *application) startConsole() {
-	session := StartSession(app.config)
+func (app *application) newExchange() (Exchange, error) {
+	exchange := NewBinanceExchange(binance.NewClient(app.config.APIKey, app.config.APISecret))
+	if !app.config.Paper {
+		return exchange, nil
+	}
+
+	wallet, err := ParseWallet(app.config.PaperWallet)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Println("PAPER TRADING MODE: orders are only simulated")
+	return NewPaperExchange(exchange, wallet), nil
+}
+
+func (app *application) startConsole() error {
+	exchange, err := app.newExchange()
+	if err != nil {
+		return err
+	}
+	session := StartSession(exchange, app.config)
 	go func() {
 		for {
 			fmt.Println(session.Get())
@@ -80,6 +114,7 @@ func (app *application) startConsole() {
 			session.Put(text)
 		}
 	}()
+	return nil
 }
 
 func (app *application) startHTTP() {
@@ -0,0 +1,617 @@
+// Package orderbook simulates the orders and the wallet of an exchange account.
+// It is the matching engine of the paper trading and of the fake binance server,
+// so that both place, lock, match and fill orders by the same rules.
+package orderbook
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/adshao/go-binance/v2"
+	"github.com/adshao/go-binance/v2/common"
+	"github.com/shopspring/decimal"
+)
+
+// OrderRequest describes a new order. Market orders have either a quantity or a quote quantity.
+type OrderRequest struct {
+	Symbol        string
+	Side          binance.SideType
+	Type          binance.OrderType
+	TimeInForce   binance.TimeInForceType
+	Quantity      string
+	QuoteOrderQty string
+	Price         string
+	StopPrice     string
+}
+
+// OCORequest describes a new one-cancels-the-other order list of a limit maker and a stop loss limit order
+type OCORequest struct {
+	Symbol         string
+	Side           binance.SideType
+	Quantity       string
+	Price          string
+	StopPrice      string
+	StopLimitPrice string // the stop price is used, if it is empty
+}
+
+// Book holds the symbols, balances, orders and trades of the simulated account.
+// It is not safe for concurrent use, the owner has to serialize the calls.
+type Book struct {
+	// OnOrder is called with the execution report of a filled or expired order
+	OnOrder func(update binance.WsOrderUpdate)
+	// OnBalances is called with the balances of the assets changed by an order
+	OnBalances func(update binance.WsAccountUpdateList)
+
+	prefix   string
+	symbols  map[string]binance.Symbol
+	balances map[string]*balance
+	orders   []*order
+	trades   []*binance.TradeV3
+	nextID   int64
+	nextList int64
+}
+
+type balance struct {
+	free   decimal.Decimal
+	locked decimal.Decimal
+}
+
+// order is an order of the book together with the funds locked for it
+type order struct {
+	*binance.Order
+	lock *lock
+}
+
+// lock are the funds locked by an open order.
+// The legs of an order list share one lock.
+type lock struct {
+	asset  string
+	amount decimal.Decimal
+}
+
+// New creates an empty book. The client order ids start with the prefix, e.g. "paper-1".
+func New(prefix string) *Book {
+	return &Book{
+		prefix:   prefix,
+		symbols:  make(map[string]binance.Symbol),
+		balances: make(map[string]*balance),
+		nextID:   1,
+	}
+}
+
+// AddSymbol registers a tradable symbol
+func (b *Book) AddSymbol(symbol binance.Symbol) {
+	b.symbols[symbol.Symbol] = symbol
+}
+
+// HasSymbol returns true, if the symbol is registered
+func (b *Book) HasSymbol(symbol string) bool {
+	_, exist := b.symbols[symbol]
+	return exist
+}
+
+// SetBalance sets the free and locked amount of an asset
+func (b *Book) SetBalance(asset string, free, locked decimal.Decimal) {
+	b.balances[asset] = &balance{free: free, locked: locked}
+}
+
+// Balances returns all balances ordered by asset
+func (b *Book) Balances() []binance.Balance {
+	var result []binance.Balance
+	for asset, bal := range b.balances {
+		result = append(result, binance.Balance{Asset: asset, Free: format(bal.free), Locked: format(bal.locked)})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Asset < result[j].Asset })
+	return result
+}
+
+// CreateOrder places an order and locks its funds. Market orders and limits crossing the current price
+// are filled immediately at the current price. Stop orders start working, once the price reaches their stop.
+// A zero current price is unknown: market orders are rejected and limits are not matched.
+func (b *Book) CreateOrder(req OrderRequest, current decimal.Decimal) (*binance.CreateOrderResponse, error) {
+	symbol, exist := b.symbols[req.Symbol]
+	if !exist {
+		return nil, &common.APIError{Code: -1121, Message: "Invalid symbol."}
+	}
+	price := parse(req.Price)
+	if req.Type == binance.OrderTypeMarket {
+		price = current
+	}
+	if !price.IsPositive() {
+		return nil, &common.APIError{Code: -1013, Message: "Invalid price."}
+	}
+	qty := parse(req.Quantity)
+	if req.Type == binance.OrderTypeMarket && req.Quantity == "" {
+		qty = parse(req.QuoteOrderQty).DivRound(price, 16).Truncate(8)
+	}
+	if !qty.IsPositive() {
+		return nil, &common.APIError{Code: -1013, Message: "Invalid quantity."}
+	}
+	stopPrice := decimal.Zero
+	switch req.Type {
+	case binance.OrderTypeLimit, binance.OrderTypeLimitMaker, binance.OrderTypeMarket:
+	case binance.OrderTypeStopLossLimit, binance.OrderTypeTakeProfitLimit:
+		stopPrice = parse(req.StopPrice)
+		if !stopPrice.IsPositive() {
+			return nil, &common.APIError{Code: -1013, Message: "Invalid stop price."}
+		}
+	default:
+		return nil, &common.APIError{Code: -1116, Message: "Invalid orderType."}
+	}
+
+	o := b.newOrder(req.Symbol, req.Side, req.Type, qty, price, stopPrice)
+	o.TimeInForce = req.TimeInForce
+	if req.QuoteOrderQty != "" {
+		o.OrigQuoteOrderQuantity = format(parse(req.QuoteOrderQty))
+	}
+	if !o.IsWorking && current.IsPositive() && triggers(o.Order, current) {
+		return nil, &common.APIError{Code: -2010, Message: "Stop price would trigger immediately."}
+	}
+	var err error
+	if o.lock, err = b.lockFunds(symbol, req.Side, qty, price); err != nil {
+		return nil, err
+	}
+	b.nextID++
+	b.orders = append(b.orders, o)
+
+	var fills []*binance.Fill
+	if o.IsWorking && current.IsPositive() && crosses(o.Order, current) {
+		trade := b.fill(o, current)
+		fills = append(fills, &binance.Fill{
+			TradeID:         trade.ID,
+			Price:           trade.Price,
+			Quantity:        trade.Quantity,
+			Commission:      trade.Commission,
+			CommissionAsset: trade.CommissionAsset,
+		})
+	} else {
+		b.publishBalances(o.lock.asset)
+	}
+
+	return &binance.CreateOrderResponse{
+		Symbol:                   o.Symbol,
+		OrderID:                  o.OrderID,
+		ClientOrderID:            o.ClientOrderID,
+		TransactTime:             o.UpdateTime,
+		Price:                    o.Price,
+		OrigQuantity:             o.OrigQuantity,
+		ExecutedQuantity:         o.ExecutedQuantity,
+		CummulativeQuoteQuantity: o.CummulativeQuoteQuantity,
+		Status:                   o.Status,
+		TimeInForce:              o.TimeInForce,
+		Type:                     o.Type,
+		Side:                     o.Side,
+		Fills:                    fills,
+	}, nil
+}
+
+// CreateOCO places a limit maker and a stop loss limit order, which share the locked funds.
+// A buy locks the quote amount of the higher limit. If the current price is known,
+// it has to be between the limit and the stop price.
+func (b *Book) CreateOCO(req OCORequest, current decimal.Decimal) (*binance.CreateOCOResponse, error) {
+	symbol, exist := b.symbols[req.Symbol]
+	if !exist {
+		return nil, &common.APIError{Code: -1121, Message: "Invalid symbol."}
+	}
+	if req.StopLimitPrice == "" {
+		req.StopLimitPrice = req.StopPrice
+	}
+	qty, price, stopPrice, stopLimitPrice := parse(req.Quantity), parse(req.Price), parse(req.StopPrice), parse(req.StopLimitPrice)
+	for _, d := range []decimal.Decimal{qty, price, stopPrice, stopLimitPrice} {
+		if !d.IsPositive() {
+			return nil, &common.APIError{Code: -1013, Message: "Invalid quantity or price."}
+		}
+	}
+	validPrices := price.GreaterThan(current) && current.GreaterThan(stopPrice)
+	if req.Side == binance.SideTypeBuy {
+		validPrices = price.LessThan(current) && current.LessThan(stopPrice)
+	}
+	if current.IsPositive() && !validPrices {
+		return nil, &common.APIError{Code: -1013, Message: "The relationship of the prices for the orders is not correct."}
+	}
+
+	l, err := b.lockFunds(symbol, req.Side, qty, decimal.Max(price, stopLimitPrice))
+	if err != nil {
+		return nil, err
+	}
+	stopLeg := b.newOrder(req.Symbol, req.Side, binance.OrderTypeStopLossLimit, qty, stopLimitPrice, stopPrice)
+	b.nextID++
+	limitLeg := b.newOrder(req.Symbol, req.Side, binance.OrderTypeLimitMaker, qty, price, decimal.Zero)
+	b.nextID++
+
+	listID := b.nextList
+	b.nextList++
+	response := &binance.CreateOCOResponse{
+		OrderListID:       listID,
+		ContingencyType:   "OCO",
+		ListStatusType:    "EXEC_STARTED",
+		ListOrderStatus:   "EXECUTING",
+		ListClientOrderID: fmt.Sprintf("%v-list-%v", b.prefix, listID),
+		TransactionTime:   nowMillis(),
+		Symbol:            req.Symbol,
+	}
+	for _, leg := range []*order{stopLeg, limitLeg} {
+		leg.OrderListId = listID
+		leg.TimeInForce = binance.TimeInForceTypeGTC
+		leg.lock = l
+		b.orders = append(b.orders, leg)
+		response.Orders = append(response.Orders, &binance.OCOOrder{
+			Symbol:        leg.Symbol,
+			OrderID:       leg.OrderID,
+			ClientOrderID: leg.ClientOrderID,
+		})
+		response.OrderReports = append(response.OrderReports, &binance.OCOOrderReport{
+			Symbol:                   leg.Symbol,
+			OrderID:                  leg.OrderID,
+			OrderListID:              listID,
+			ClientOrderID:            leg.ClientOrderID,
+			TransactionTime:          leg.Time,
+			Price:                    leg.Price,
+			OrigQuantity:             leg.OrigQuantity,
+			ExecutedQuantity:         leg.ExecutedQuantity,
+			CummulativeQuoteQuantity: leg.CummulativeQuoteQuantity,
+			Status:                   leg.Status,
+			TimeInForce:              leg.TimeInForce,
+			Type:                     leg.Type,
+			Side:                     leg.Side,
+			StopPrice:                leg.StopPrice,
+		})
+	}
+	b.publishBalances(l.asset)
+	return response, nil
+}
+
+// Cancel cancels the open order and the other legs of its order list, and releases the locked funds
+func (b *Book) Cancel(symbol string, orderID int64) (*binance.Order, error) {
+	o := b.find(symbol, orderID)
+	if o == nil || !isOpen(o.Order) {
+		return nil, &common.APIError{Code: -2011, Message: "Unknown order sent."}
+	}
+	for _, leg := range b.legs(o) {
+		if isOpen(leg.Order) {
+			b.cancel(leg)
+		}
+	}
+	c := *o.Order
+	return &c, nil
+}
+
+// CancelOpenOrders cancels all open orders of the symbol and returns them
+func (b *Book) CancelOpenOrders(symbol string) []*binance.Order {
+	var result []*binance.Order
+	for _, o := range b.orders {
+		if o.Symbol == symbol && isOpen(o.Order) {
+			b.cancel(o)
+			c := *o.Order
+			result = append(result, &c)
+		}
+	}
+	return result
+}
+
+// Order returns a copy of the order
+func (b *Book) Order(symbol string, orderID int64) (*binance.Order, bool) {
+	o := b.find(symbol, orderID)
+	if o == nil {
+		return nil, false
+	}
+	c := *o.Order
+	return &c, true
+}
+
+// Orders returns copies of the orders of the symbol, or of all symbols if it is empty
+func (b *Book) Orders(symbol string, onlyOpen bool) []*binance.Order {
+	var result []*binance.Order
+	for _, o := range b.orders {
+		if (symbol == "" || o.Symbol == symbol) && (!onlyOpen || isOpen(o.Order)) {
+			c := *o.Order
+			result = append(result, &c)
+		}
+	}
+	return result
+}
+
+// OpenSymbols returns the symbols with open orders
+func (b *Book) OpenSymbols() []string {
+	var result []string
+	seen := make(map[string]bool)
+	for _, o := range b.orders {
+		if isOpen(o.Order) && !seen[o.Symbol] {
+			seen[o.Symbol] = true
+			result = append(result, o.Symbol)
+		}
+	}
+	return result
+}
+
+// AddTrade adds a trade, e.g. from the past. The id is assigned by the book.
+func (b *Book) AddTrade(trade binance.TradeV3) {
+	trade.ID = int64(len(b.trades) + 1)
+	b.trades = append(b.trades, &trade)
+}
+
+// Trades returns copies of the trades of the symbol in ascending order
+func (b *Book) Trades(symbol string) []*binance.TradeV3 {
+	var result []*binance.TradeV3
+	for _, t := range b.trades {
+		if t.Symbol == symbol {
+			c := *t
+			result = append(result, &c)
+		}
+	}
+	return result
+}
+
+// Match fills all open orders of the symbol, which are crossed by the price.
+// Stop orders start working, once their stop price is reached.
+func (b *Book) Match(symbol string, price decimal.Decimal) {
+	if !price.IsPositive() {
+		return
+	}
+	for _, o := range b.orders {
+		if o.Symbol != symbol || !isOpen(o.Order) {
+			continue
+		}
+		fillPrice := parse(o.Price)
+		if !o.IsWorking {
+			if !triggers(o.Order, price) {
+				continue
+			}
+			// a triggered limit, which is crossed already, takes the current price
+			o.IsWorking = true
+			o.UpdateTime = nowMillis()
+			fillPrice = price
+		}
+		if crosses(o.Order, price) {
+			b.fill(o, fillPrice)
+		}
+	}
+}
+
+// Fill executes the open order at the price, regardless of the market
+func (b *Book) Fill(orderID int64, price decimal.Decimal) error {
+	for _, o := range b.orders {
+		if o.OrderID == orderID {
+			if !isOpen(o.Order) {
+				return fmt.Errorf("order %v is not open", orderID)
+			}
+			b.fill(o, price)
+			return nil
+		}
+	}
+	return fmt.Errorf("order %v not found", orderID)
+}
+
+func (b *Book) newOrder(symbol string, side binance.SideType, orderType binance.OrderType, qty, price, stopPrice decimal.Decimal) *order {
+	return &order{
+		Order: &binance.Order{
+			Symbol:                   symbol,
+			OrderID:                  b.nextID,
+			OrderListId:              -1,
+			ClientOrderID:            fmt.Sprintf("%v-%v", b.prefix, b.nextID),
+			Price:                    format(price),
+			OrigQuantity:             format(qty),
+			ExecutedQuantity:         format(decimal.Zero),
+			CummulativeQuoteQuantity: format(decimal.Zero),
+			Status:                   binance.OrderStatusTypeNew,
+			Type:                     orderType,
+			Side:                     side,
+			StopPrice:                format(stopPrice),
+			Time:                     nowMillis(),
+			UpdateTime:               nowMillis(),
+			IsWorking:                orderType == binance.OrderTypeLimit || orderType == binance.OrderTypeLimitMaker || orderType == binance.OrderTypeMarket,
+			OrigQuoteOrderQuantity:   format(decimal.Zero),
+		},
+	}
+}
+
+// lockFunds moves the funds needed for an order from free to locked
+func (b *Book) lockFunds(symbol binance.Symbol, side binance.SideType, qty, price decimal.Decimal) (*lock, error) {
+	l := &lock{asset: symbol.BaseAsset, amount: qty}
+	if side == binance.SideTypeBuy {
+		l = &lock{asset: symbol.QuoteAsset, amount: qty.Mul(price)}
+	}
+	bal := b.balance(l.asset)
+	if bal.free.LessThan(l.amount) {
+		return nil, &common.APIError{Code: -2010, Message: "Account has insufficient balance for requested action."}
+	}
+	bal.free = bal.free.Sub(l.amount)
+	bal.locked = bal.locked.Add(l.amount)
+	return l, nil
+}
+
+// unlock moves the funds of the lock back to free. Unlocking twice has no effect.
+func (b *Book) unlock(l *lock) {
+	bal := b.balance(l.asset)
+	bal.locked = bal.locked.Sub(l.amount)
+	bal.free = bal.free.Add(l.amount)
+	l.amount = decimal.Zero
+}
+
+func (b *Book) cancel(o *order) {
+	b.unlock(o.lock)
+	o.Status = binance.OrderStatusTypeCanceled
+	o.IsWorking = false
+	o.UpdateTime = nowMillis()
+	b.publishBalances(o.lock.asset)
+}
+
+// fill executes the whole order at the price and moves the funds between the balances.
+// A buy below its limit gets the rest of the locked funds back. The other legs of an order list expire.
+func (b *Book) fill(o *order, price decimal.Decimal) *binance.TradeV3 {
+	s := b.symbols[o.Symbol]
+	qty := parse(o.OrigQuantity)
+	quoteQty := qty.Mul(price)
+
+	b.unlock(o.lock)
+	base, quote := b.balance(s.BaseAsset), b.balance(s.QuoteAsset)
+	if o.Side == binance.SideTypeBuy {
+		quote.free = quote.free.Sub(quoteQty)
+		base.free = base.free.Add(qty)
+	} else {
+		base.free = base.free.Sub(qty)
+		quote.free = quote.free.Add(quoteQty)
+	}
+
+	o.ExecutedQuantity = o.OrigQuantity
+	o.CummulativeQuoteQuantity = format(quoteQty)
+	o.Status = binance.OrderStatusTypeFilled
+	o.IsWorking = false
+	o.UpdateTime = nowMillis()
+	trade := &binance.TradeV3{
+		ID:              int64(len(b.trades) + 1),
+		Symbol:          o.Symbol,
+		OrderID:         o.OrderID,
+		OrderListId:     o.OrderListId,
+		Price:           format(price),
+		Quantity:        o.OrigQuantity,
+		QuoteQuantity:   o.CummulativeQuoteQuantity,
+		Commission:      format(decimal.Zero),
+		CommissionAsset: s.QuoteAsset,
+		Time:            o.UpdateTime,
+		IsBuyer:         o.Side == binance.SideTypeBuy,
+		IsBestMatch:     true,
+	}
+	b.trades = append(b.trades, trade)
+	b.publishOrder(o.Order, "TRADE", qty, price)
+
+	for _, leg := range b.legs(o) {
+		if leg != o && isOpen(leg.Order) {
+			leg.Status = binance.OrderStatusTypeExpired
+			leg.IsWorking = false
+			leg.UpdateTime = o.UpdateTime
+			b.publishOrder(leg.Order, "EXPIRED", decimal.Zero, decimal.Zero)
+		}
+	}
+	b.publishBalances(s.BaseAsset, s.QuoteAsset)
+	return trade
+}
+
+// legs returns all orders of the order list of the order, or the order itself
+func (b *Book) legs(o *order) []*order {
+	if o.OrderListId < 0 {
+		return []*order{o}
+	}
+	var legs []*order
+	for _, other := range b.orders {
+		if other.Symbol == o.Symbol && other.OrderListId == o.OrderListId {
+			legs = append(legs, other)
+		}
+	}
+	return legs
+}
+
+func (b *Book) find(symbol string, orderID int64) *order {
+	for _, o := range b.orders {
+		if o.Symbol == symbol && o.OrderID == orderID {
+			return o
+		}
+	}
+	return nil
+}
+
+// balance returns the balance of the asset, it is created if not existing
+func (b *Book) balance(asset string) *balance {
+	bal, exist := b.balances[asset]
+	if !exist {
+		bal = &balance{}
+		b.balances[asset] = bal
+	}
+	return bal
+}
+
+func (b *Book) publishOrder(o *binance.Order, executionType string, lastQty, lastPrice decimal.Decimal) {
+	if b.OnOrder == nil {
+		return
+	}
+	b.OnOrder(binance.WsOrderUpdate{
+		Symbol:            o.Symbol,
+		ClientOrderId:     o.ClientOrderID,
+		Side:              string(o.Side),
+		Type:              string(o.Type),
+		TimeInForce:       o.TimeInForce,
+		Volume:            o.OrigQuantity,
+		Price:             o.Price,
+		StopPrice:         o.StopPrice,
+		OrderListId:       o.OrderListId,
+		ExecutionType:     executionType,
+		Status:            string(o.Status),
+		Id:                o.OrderID,
+		LatestVolume:      format(lastQty),
+		FilledVolume:      o.ExecutedQuantity,
+		LatestPrice:       format(lastPrice),
+		FeeCost:           format(decimal.Zero),
+		TransactionTime:   o.UpdateTime,
+		CreateTime:        o.Time,
+		FilledQuoteVolume: o.CummulativeQuoteQuantity,
+		LatestQuoteVolume: format(lastQty.Mul(lastPrice)),
+	})
+}
+
+func (b *Book) publishBalances(assets ...string) {
+	if b.OnBalances == nil {
+		return
+	}
+	update := binance.WsAccountUpdateList{AccountUpdateTime: nowMillis()}
+	for _, asset := range assets {
+		bal := b.balance(asset)
+		update.WsAccountUpdates = append(update.WsAccountUpdates, binance.WsAccountUpdate{
+			Asset:  asset,
+			Free:   format(bal.free),
+			Locked: format(bal.locked),
+		})
+	}
+	b.OnBalances(update)
+}
+
+func isOpen(o *binance.Order) bool {
+	return o.Status == binance.OrderStatusTypeNew || o.Status == binance.OrderStatusTypePartiallyFilled
+}
+
+// crosses returns true, if the limit of the order is reached by the price
+func crosses(o *binance.Order, price decimal.Decimal) bool {
+	limit := parse(o.Price)
+	if o.Side == binance.SideTypeBuy {
+		return price.LessThanOrEqual(limit)
+	}
+	return price.GreaterThanOrEqual(limit)
+}
+
+// triggers returns true, if the stop price of a stop loss or take profit order is reached by the price
+func triggers(o *binance.Order, price decimal.Decimal) bool {
+	stop := parse(o.StopPrice)
+	below := price.LessThanOrEqual(stop)
+	above := price.GreaterThanOrEqual(stop)
+	switch o.Type {
+	case binance.OrderTypeStopLossLimit:
+		if o.Side == binance.SideTypeSell {
+			return below
+		}
+		return above
+	case binance.OrderTypeTakeProfitLimit:
+		if o.Side == binance.SideTypeSell {
+			return above
+		}
+		return below
+	}
+	return true
+}
+
+// parse parses a decimal string, invalid and empty values are zero
+func parse(value string) decimal.Decimal {
+	d, err := decimal.NewFromString(value)
+	if err != nil {
+		return decimal.Zero
+	}
+	return d
+}
+
+// format formats a decimal with 8 digits, like the binance api
+func format(d decimal.Decimal) string {
+	return d.StringFixed(8)
+}
+
+func nowMillis() int64 {
+	return time.Now().UnixNano() / int64(time.Millisecond)
+}
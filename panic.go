@@ -0,0 +1,137 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/adshao/go-binance/v2"
+)
+
+// Panic cancels all open orders of all symbols. With the argument "sell",
+// all balances besides BTC, EUR, the reporting currency and the preferred quote are sold at market afterwards.
+// The orders are canceled without confirmation, only the market sells have to be confirmed.
+// The sells are only checked against the blacklist, because the kill switch only reduces the risk.
+func (sess *Session) Panic(arg string) {
+	sell := arg == "sell"
+	if arg != "" && !sell {
+		sess.Answer("USAGE: panic [sell]")
+		return
+	}
+
+	if sess.trailing != nil {
+		sess.stopTrailing()
+	}
+
+	orders, err := sess.exchange.OpenOrders("")
+	if err != nil {
+		sess.Answerf("ERROR ON LIST ORDERS: %v", err)
+		return
+	}
+	var symbols []string
+	bySymbol := make(map[string]int)
+	for _, order := range orders {
+		if bySymbol[order.Symbol] == 0 {
+			symbols = append(symbols, order.Symbol)
+		}
+		bySymbol[order.Symbol]++
+		sess.Answerf("%v %v %v @%v (%v)", order.Side, FromS(order.OrigQuantity).StringCompact(), order.Symbol, order.Price, order.Status)
+	}
+	sort.Strings(symbols)
+	if len(orders) == 0 {
+		sess.Answer("no open orders")
+	}
+
+	for _, symbol := range symbols {
+		if sess.dryRun {
+			sess.Answerf("DRY RUN: cancel %v orders of %v", bySymbol[symbol], symbol)
+			continue
+		}
+		if err := sess.exchange.CancelOpenOrders(symbol); err != nil {
+			sess.Answerf("ERROR ON CANCEL ORDERS OF %v: %v", symbol, err)
+			continue
+		}
+		sess.Answerf("canceled %v orders of %v", bySymbol[symbol], symbol)
+	}
+
+	if sell {
+		if len(symbols) > 0 && !sess.dryRun {
+			time.Sleep(time.Millisecond * 200)
+		}
+		sess.sellEverything()
+	}
+
+	sess.Answer("")
+	sess.ShowBalances()
+}
+
+// sellEverything sells the free balance of all assets besides BTC, EUR, the reporting currency
+// and the preferred quote at market, to the preferred quote or to the first other quote with a market.
+// The sells are shown and confirmed together.
+func (sess *Session) sellEverything() {
+	account, err := sess.exchange.Account()
+	if err != nil {
+		sess.Answerf("ERROR ON FETCHING ACCOUNT INFO: %v", err)
+		return
+	}
+	var sells []orderPreview
+	for _, b := range account.Balances {
+		free := FromS(b.Free)
+		if b.Asset == "BTC" || b.Asset == "EUR" || b.Asset == currencies[sess.currency] || b.Asset == sess.quote || free.Sign() <= 0 {
+			continue
+		}
+		symbol := sess.market(b.Asset)
+		if symbol == nil {
+			sess.Answerf("SKIPPED %v: no market to %v", b.Asset, strings.Join(sess.quotes(), ", "))
+			continue
+		}
+
+		filters := NewSymbolFilters(symbol)
+		qty := filters.Quantity(free)
+		price := sess.livePrice(symbol.Symbol)
+		if err := filters.Check(qty, price); err != nil {
+			sess.Answerf("SKIPPED %v %v: %v", free.StringCompact(), b.Asset, err)
+			continue
+		}
+		sells = append(sells, orderPreview{
+			side:      binance.SideTypeSell,
+			orderType: binance.OrderTypeMarket,
+			symbol:    symbol.Symbol,
+			qty:       qty,
+			price:     price,
+			value:     qty.Mult(price),
+			distance:  FromI(0),
+		})
+	}
+	if len(sells) == 0 {
+		sess.Answer("nothing to sell")
+		return
+	}
+
+	if !sess.dryRun {
+		for _, p := range sells {
+			sess.Answer(sess.formatPreview(p))
+		}
+		if !sess.confirm() {
+			return
+		}
+	}
+	sess.exiting = true
+	for _, p := range sells {
+		if !sess.approve(p) {
+			continue
+		}
+		order, err := sess.exchange.CreateOrder(OrderRequest{
+			Symbol:   p.symbol,
+			Side:     binance.SideTypeSell,
+			Type:     binance.OrderTypeMarket,
+			Quantity: p.qty.String(),
+		})
+		if err != nil {
+			sess.Answerf("ERROR ON MARKET SELL FOR %v of %v: %v", p.qty.StringCompact(), p.symbol, err)
+			continue
+		}
+		sess.journalMarketOrder(order)
+		sess.Answerf("%v [%v of %v for %v %v (%v)]", order.Side, order.ExecutedQuantity, order.Symbol, order.CummulativeQuoteQuantity, sess.allSymbols[p.symbol].QuoteAsset, order.Status)
+	}
+}
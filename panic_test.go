@@ -0,0 +1,79 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/adshao/go-binance/v2"
+	"github.com/smancke/trading-shell/binancefake"
+	"github.com/smancke/trading-shell/config"
+)
+
+// marketSells returns the market sells of the fake server
+func (shell *testShell) marketSells() []binance.Order {
+	var sells []binance.Order
+	for _, o := range shell.srv.Orders() {
+		if o.Type == binance.OrderTypeMarket && o.Side == binance.SideTypeSell {
+			sells = append(sells, o)
+		}
+	}
+	return sells
+}
+
+func TestPanic(t *testing.T) {
+	shell := newTestShell(t, func(srv *binancefake.Server, c *config.Config) {
+		srv.AddSymbol("ABCBTC", "ABC", "BTC")
+		srv.SetPrice("ABCBTC", "0.001")
+		srv.SetBalance("XYZ", "1000", "0")
+		srv.SetBalance("ABC", "10", "0")
+		c.ConfirmAbove = 0
+		c.ConfirmDistance = 0
+		c.RiskBlacklist = "ABC"
+	})
+	shell.run("xyzbtc")
+
+	// the orders are canceled without confirmation
+	shell.run("buy 0.5")
+	if out := shell.run("panic"); !strings.Contains(out, "canceled 1 orders of XYZBTC") || len(shell.openOrders()) != 0 {
+		t.Errorf("expected the buy to be canceled, got:\n%v", out)
+	}
+
+	// declining the sells still cancels the orders
+	shell.run("buy 0.5")
+	out := shell.answer("panic sell", "n")
+	if !strings.Contains(out, "CONFIRM WITH y") || len(shell.openOrders()) != 0 || len(shell.marketSells()) != 0 {
+		t.Errorf("expected the orders to be canceled and nothing sold, got:\n%v", out)
+	}
+
+	// the confirmed sells are checked against the blacklist
+	out = shell.answer("panic sell", "y")
+	if !strings.Contains(out, "ORDER BLOCKED BY RISK LIMIT: ABCBTC is blacklisted") {
+		t.Errorf("expected the sell of ABC to be blocked, got:\n%v", out)
+	}
+	sells := shell.marketSells()
+	if len(sells) != 1 || sells[0].Symbol != "XYZBTC" || FromS(sells[0].ExecutedQuantity).Cmp(FromI(1000)) != 0 {
+		t.Errorf("expected a market sell of 1000 XYZ, got %+v", sells)
+	}
+	if !strings.Contains(out, "BTC: 0.02000000") {
+		t.Errorf("expected the balance summary with the sold XYZ, got:\n%v", out)
+	}
+}
+
+func TestPanicDryRun(t *testing.T) {
+	shell := newTestShell(t, func(srv *binancefake.Server, c *config.Config) {
+		srv.SetBalance("XYZ", "1000", "0")
+		c.ConfirmAbove = 0
+		c.ConfirmDistance = 0
+	})
+	shell.run("xyzbtc")
+	shell.run("buy 0.5")
+	shell.run("dry-run on")
+
+	out := shell.run("panic sell")
+	if !strings.Contains(out, "DRY RUN: cancel 1 orders of XYZBTC") || !strings.Contains(out, "DRY RUN: SELL MARKET 1000 XYZBTC") {
+		t.Errorf("expected the cancel and the sell to be printed, got:\n%v", out)
+	}
+	if len(shell.openOrders()) != 1 || len(shell.marketSells()) != 0 {
+		t.Errorf("expected nothing to be sent, got %+v", shell.srv.Orders())
+	}
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adshao/go-binance/v2"
+	"github.com/adshao/go-binance/v2/common"
+	"github.com/shopspring/decimal"
+	"github.com/smancke/trading-shell/orderbook"
+)
+
+// PaperExchange simulates order book and wallet in memory.
+// The market data (prices, stats, exchange info) is taken from the underlying
+// market exchange, while orders are only placed in the simulated order book.
+type PaperExchange struct {
+	market  Exchange
+	mutex   sync.Mutex
+	book    *orderbook.Book
+	loaded  bool // the symbols of the market are added to the book
+	streams []*paperUserStream
+}
+
+// paperUserStream delivers the order and balance updates of the simulation to a subscriber
+type paperUserStream struct {
+	events chan *binance.WsUserDataEvent
+	stopC  chan struct{}
+}
+
+func NewPaperExchange(market Exchange, wallet map[string]F) *PaperExchange {
+	ex := &PaperExchange{
+		market: market,
+		book:   orderbook.New("paper"),
+	}
+	for asset, amount := range wallet {
+		ex.book.SetBalance(asset, amount.V, decimal.Zero)
+	}
+	ex.book.OnOrder = func(update binance.WsOrderUpdate) {
+		ex.publish(&binance.WsUserDataEvent{
+			Event:       binance.UserDataEventTypeExecutionReport,
+			Time:        update.TransactionTime,
+			OrderUpdate: update,
+		})
+	}
+	ex.book.OnBalances = func(update binance.WsAccountUpdateList) {
+		ex.publish(&binance.WsUserDataEvent{
+			Event:         binance.UserDataEventTypeOutboundAccountPosition,
+			Time:          update.AccountUpdateTime,
+			AccountUpdate: update,
+		})
+	}
+	return ex
+}
+
+// ParseWallet parses a wallet definition of the form "BTC:0.01,ETH:1"
+func ParseWallet(s string) (map[string]F, error) {
+	wallet := make(map[string]F)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		pair := strings.SplitN(entry, ":", 2)
+		if len(pair) != 2 {
+			return nil, fmt.Errorf("invalid wallet entry %q, expected ASSET:AMOUNT", entry)
+		}
+		amount := FromS(pair[1])
+		if !amount.Valid() || amount.Sign() < 0 {
+			return nil, fmt.Errorf("invalid amount in wallet entry %q", entry)
+		}
+		wallet[strings.ToUpper(pair[0])] = amount
+	}
+	return wallet, nil
+}
+
+func (ex *PaperExchange) Prices(symbol string) ([]*binance.SymbolPrice, error) {
+	prices, err := ex.market.Prices(symbol)
+	if err != nil {
+		return nil, err
+	}
+	ex.mutex.Lock()
+	defer ex.mutex.Unlock()
+	for _, p := range prices {
+		ex.book.Match(p.Symbol, FromS(p.Price).V)
+	}
+	return prices, nil
+}
+
+func (ex *PaperExchange) AvgPrice(symbol string) (*binance.AvgPrice, error) {
+	return ex.market.AvgPrice(symbol)
+}
+
+func (ex *PaperExchange) PriceChangeStats() ([]*binance.PriceChangeStats, error) {
+	return ex.market.PriceChangeStats()
+}
+
+func (ex *PaperExchange) ExchangeInfo() (*binance.ExchangeInfo, error) {
+	return ex.market.ExchangeInfo()
+}
+
+func (ex *PaperExchange) Account() (*binance.Account, error) {
+	ex.update()
+	ex.mutex.Lock()
+	defer ex.mutex.Unlock()
+	return &binance.Account{
+		CanTrade:    true,
+		AccountType: "SPOT",
+		UpdateTime:  uint64(time.Now().UnixNano() / int64(time.Millisecond)),
+		Balances:    ex.book.Balances(),
+	}, nil
+}
+
+func (ex *PaperExchange) CreateOrder(req OrderRequest) (*binance.CreateOrderResponse, error) {
+	if err := ex.checkSymbol(req.Symbol); err != nil {
+		return nil, err
+	}
+	currentPrice := Price(ex.market, req.Symbol)
+	if !currentPrice.Valid() {
+		return nil, currentPrice.Err
+	}
+	ex.mutex.Lock()
+	defer ex.mutex.Unlock()
+	return ex.book.CreateOrder(orderbook.OrderRequest{
+		Symbol:        req.Symbol,
+		Side:          req.Side,
+		Type:          req.Type,
+		TimeInForce:   req.TimeInForce,
+		Quantity:      req.Quantity,
+		QuoteOrderQty: req.QuoteOrderQty,
+		Price:         req.Price,
+		StopPrice:     req.StopPrice,
+	}, currentPrice.V)
+}
+
+func (ex *PaperExchange) CreateOCO(req OCORequest) (*binance.CreateOCOResponse, error) {
+	if err := ex.checkSymbol(req.Symbol); err != nil {
+		return nil, err
+	}
+	currentPrice := Price(ex.market, req.Symbol)
+	if !currentPrice.Valid() {
+		return nil, currentPrice.Err
+	}
+	ex.mutex.Lock()
+	defer ex.mutex.Unlock()
+	return ex.book.CreateOCO(orderbook.OCORequest{
+		Symbol:         req.Symbol,
+		Side:           req.Side,
+		Quantity:       req.Quantity,
+		Price:          req.Price,
+		StopPrice:      req.StopPrice,
+		StopLimitPrice: req.StopLimitPrice,
+	}, currentPrice.V)
+}
+
+func (ex *PaperExchange) CancelOpenOrders(symbol string) error {
+	ex.mutex.Lock()
+	defer ex.mutex.Unlock()
+	ex.book.CancelOpenOrders(symbol)
+	return nil
+}
+
+func (ex *PaperExchange) OpenOrders(symbol string) ([]*binance.Order, error) {
+	ex.update()
+	ex.mutex.Lock()
+	defer ex.mutex.Unlock()
+	return ex.book.Orders(symbol, true), nil
+}
+
+func (ex *PaperExchange) Orders(symbol string, limit int) ([]*binance.Order, error) {
+	ex.update()
+	ex.mutex.Lock()
+	defer ex.mutex.Unlock()
+	result := ex.book.Orders(symbol, false)
+	if len(result) > limit {
+		result = result[len(result)-limit:]
+	}
+	return result, nil
+}
+
+func (ex *PaperExchange) Trades(symbol string, limit int) ([]*binance.TradeV3, error) {
+	ex.update()
+	ex.mutex.Lock()
+	defer ex.mutex.Unlock()
+	result := ex.book.Trades(symbol)
+	if len(result) > limit {
+		result = result[len(result)-limit:]
+	}
+	return result, nil
+}
+
+func (ex *PaperExchange) TradesFrom(symbol string, fromID int64, limit int) ([]*binance.TradeV3, error) {
+	ex.update()
+	ex.mutex.Lock()
+	defer ex.mutex.Unlock()
+	var result []*binance.TradeV3
+	for _, trade := range ex.book.Trades(symbol) {
+		if trade.ID >= fromID && len(result) < limit {
+			result = append(result, trade)
+		}
+	}
+	return result, nil
+}
+
+func (ex *PaperExchange) Klines(symbol, interval string, startTime int64, limit int) ([]*binance.Kline, error) {
+	return ex.market.Klines(symbol, interval, startTime, limit)
+}
+
+// TradeStream subscribes to the trades of the market and matches the open orders on every trade
+func (ex *PaperExchange) TradeStream(symbols []string, handler binance.WsCombinedTradeHandler, errHandler binance.ErrHandler) (doneC, stopC chan struct{}, err error) {
+	return ex.market.TradeStream(symbols, func(event *binance.WsCombinedTradeEvent) {
+		ex.mutex.Lock()
+		ex.book.Match(event.Data.Symbol, FromS(event.Data.Price).V)
+		ex.mutex.Unlock()
+		handler(event)
+	}, errHandler)
+}
+
+// update matches all open orders against the current market prices
+func (ex *PaperExchange) update() {
+	ex.mutex.Lock()
+	symbols := ex.book.OpenSymbols()
+	ex.mutex.Unlock()
+
+	for _, symbol := range symbols {
+		ex.Prices(symbol)
+	}
+}
+
+// UserDataStream subscribes to the order and balance updates of the simulation
+func (ex *PaperExchange) UserDataStream(handler binance.WsUserDataHandler, errHandler binance.ErrHandler) (doneC, stopC chan struct{}, err error) {
+	stream := &paperUserStream{
+		events: make(chan *binance.WsUserDataEvent, 100),
+		stopC:  make(chan struct{}),
+	}
+	ex.mutex.Lock()
+	ex.streams = append(ex.streams, stream)
+	ex.mutex.Unlock()
+
+	doneC = make(chan struct{})
+	go func() {
+		defer close(doneC)
+		for {
+			select {
+			case <-stream.stopC:
+				ex.mutex.Lock()
+				defer ex.mutex.Unlock()
+				for i := range ex.streams {
+					if ex.streams[i] == stream {
+						ex.streams = append(ex.streams[:i], ex.streams[i+1:]...)
+						break
+					}
+				}
+				return
+			case event := <-stream.events:
+				handler(event)
+			}
+		}
+	}()
+	return doneC, stream.stopC, nil
+}
+
+// publish sends the event to the user data subscribers.
+// Events for slow subscribers with a full buffer are dropped.
+// The caller has to hold the mutex.
+func (ex *PaperExchange) publish(event *binance.WsUserDataEvent) {
+	for _, stream := range ex.streams {
+		select {
+		case stream.events <- event:
+		default:
+		}
+	}
+}
+
+// checkSymbol returns an error, if the symbol is not traded on the market.
+// The symbols of the market are added to the order book on first use.
+func (ex *PaperExchange) checkSymbol(symbol string) error {
+	ex.mutex.Lock()
+	defer ex.mutex.Unlock()
+	if !ex.loaded {
+		info, err := ex.market.ExchangeInfo()
+		if err != nil {
+			return err
+		}
+		for _, s := range info.Symbols {
+			ex.book.AddSymbol(s)
+		}
+		ex.loaded = true
+	}
+	if !ex.book.HasSymbol(symbol) {
+		return &common.APIError{Code: -1121, Message: "Invalid symbol."}
+	}
+	return nil
+}
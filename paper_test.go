@@ -0,0 +1,188 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/adshao/go-binance/v2"
+	"github.com/adshao/go-binance/v2/common"
+)
+
+// testMarket is a market with scripted prices for the paper exchange
+type testMarket struct {
+	Exchange
+	prices map[string]string
+}
+
+func (m *testMarket) Prices(symbol string) ([]*binance.SymbolPrice, error) {
+	return []*binance.SymbolPrice{{Symbol: symbol, Price: m.prices[symbol]}}, nil
+}
+
+func (m *testMarket) ExchangeInfo() (*binance.ExchangeInfo, error) {
+	return &binance.ExchangeInfo{Symbols: []binance.Symbol{{Symbol: "XYZBTC", BaseAsset: "XYZ", QuoteAsset: "BTC"}}}, nil
+}
+
+func newTestPaper() (*PaperExchange, *testMarket) {
+	market := &testMarket{prices: map[string]string{"XYZBTC": "0.00001"}}
+	return NewPaperExchange(market, map[string]F{"BTC": FromS("0.01"), "XYZ": FromS("1000")}), market
+}
+
+func assertPaperBalance(t *testing.T, ex *PaperExchange, asset, free, locked string) {
+	t.Helper()
+	account, err := ex.Account()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range account.Balances {
+		if b.Asset == asset {
+			if FromS(b.Free).Cmp(FromS(free)) != 0 || FromS(b.Locked).Cmp(FromS(locked)) != 0 {
+				t.Errorf("balance of %v: expected %v free, %v locked, got %v free, %v locked", asset, free, locked, b.Free, b.Locked)
+			}
+			return
+		}
+	}
+	t.Errorf("no balance of %v", asset)
+}
+
+func assertOrderStatus(t *testing.T, ex *PaperExchange, orderID int64, status binance.OrderStatusType) {
+	t.Helper()
+	orders, err := ex.Orders("XYZBTC", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, o := range orders {
+		if o.OrderID == orderID {
+			if o.Status != status {
+				t.Errorf("order %v: expected %v, got %v", orderID, status, o.Status)
+			}
+			return
+		}
+	}
+	t.Errorf("order %v not found", orderID)
+}
+
+func paperLimit(ex *PaperExchange, side binance.SideType, qty, price string) (*binance.CreateOrderResponse, error) {
+	return ex.CreateOrder(OrderRequest{Symbol: "XYZBTC", Side: side, Type: binance.OrderTypeLimit,
+		TimeInForce: binance.TimeInForceTypeGTC, Quantity: qty, Price: price})
+}
+
+func TestPaperLimitOrder(t *testing.T) {
+	ex, market := newTestPaper()
+
+	buy, err := paperLimit(ex, binance.SideTypeBuy, "100", "0.000009")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sell, err := paperLimit(ex, binance.SideTypeSell, "500", "0.00002")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertPaperBalance(t, ex, "BTC", "0.0091", "0.0009")
+	assertPaperBalance(t, ex, "XYZ", "500", "500")
+
+	// the buy is filled at its limit, once the price crosses it
+	market.prices["XYZBTC"] = "0.000008"
+	assertOrderStatus(t, ex, buy.OrderID, binance.OrderStatusTypeFilled)
+	assertOrderStatus(t, ex, sell.OrderID, binance.OrderStatusTypeNew)
+	assertPaperBalance(t, ex, "BTC", "0.0091", "0")
+	assertPaperBalance(t, ex, "XYZ", "600", "500")
+
+	if err := ex.CancelOpenOrders("XYZBTC"); err != nil {
+		t.Fatal(err)
+	}
+	assertOrderStatus(t, ex, sell.OrderID, binance.OrderStatusTypeCanceled)
+	assertPaperBalance(t, ex, "XYZ", "1100", "0")
+}
+
+func TestPaperCrossingLimitFillsAtCurrentPrice(t *testing.T) {
+	ex, _ := newTestPaper()
+
+	buy, err := paperLimit(ex, binance.SideTypeBuy, "100", "0.00002")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buy.Status != binance.OrderStatusTypeFilled || len(buy.Fills) != 1 || FromS(buy.Fills[0].Price).Cmp(FromS("0.00001")) != 0 {
+		t.Fatalf("expected a fill at the current price, got %+v", buy)
+	}
+	assertPaperBalance(t, ex, "BTC", "0.009", "0")
+	assertPaperBalance(t, ex, "XYZ", "1100", "0")
+}
+
+func TestPaperInsufficientBalance(t *testing.T) {
+	ex, _ := newTestPaper()
+
+	_, err := paperLimit(ex, binance.SideTypeSell, "1000.1", "0.00002")
+	if apiErr, ok := err.(*common.APIError); !ok || apiErr.Code != -2010 {
+		t.Errorf("expected api error -2010, got %v", err)
+	}
+	assertPaperBalance(t, ex, "XYZ", "1000", "0")
+}
+
+func TestPaperStopLoss(t *testing.T) {
+	ex, market := newTestPaper()
+
+	stop := OrderRequest{Symbol: "XYZBTC", Side: binance.SideTypeSell, Type: binance.OrderTypeStopLossLimit,
+		TimeInForce: binance.TimeInForceTypeGTC, Quantity: "100", Price: "0.0000089", StopPrice: "0.000011"}
+	if _, err := ex.CreateOrder(stop); err == nil {
+		t.Error("expected an error for a stop above the current price")
+	}
+	stop.StopPrice = "0.000009"
+	order, err := ex.CreateOrder(stop)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertPaperBalance(t, ex, "XYZ", "900", "100")
+
+	// not triggered above the stop
+	market.prices["XYZBTC"] = "0.0000095"
+	assertOrderStatus(t, ex, order.OrderID, binance.OrderStatusTypeNew)
+
+	// triggered above its limit, it is filled at the current price
+	market.prices["XYZBTC"] = "0.000009"
+	assertOrderStatus(t, ex, order.OrderID, binance.OrderStatusTypeFilled)
+	assertPaperBalance(t, ex, "XYZ", "900", "0")
+	assertPaperBalance(t, ex, "BTC", "0.0109", "0")
+}
+
+func TestPaperOCO(t *testing.T) {
+	ex, market := newTestPaper()
+
+	oco, err := ex.CreateOCO(OCORequest{Symbol: "XYZBTC", Side: binance.SideTypeSell, Quantity: "400",
+		Price: "0.00002", StopPrice: "0.000008", StopLimitPrice: "0.0000079"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// both legs share the locked quantity
+	assertPaperBalance(t, ex, "XYZ", "600", "400")
+
+	market.prices["XYZBTC"] = "0.00002"
+	assertOrderStatus(t, ex, oco.Orders[1].OrderID, binance.OrderStatusTypeFilled)
+	assertOrderStatus(t, ex, oco.Orders[0].OrderID, binance.OrderStatusTypeExpired)
+	assertPaperBalance(t, ex, "XYZ", "600", "0")
+	assertPaperBalance(t, ex, "BTC", "0.018", "0")
+
+	_, err = ex.CreateOCO(OCORequest{Symbol: "XYZBTC", Side: binance.SideTypeSell, Quantity: "400",
+		Price: "0.00001", StopPrice: "0.000008", StopLimitPrice: "0.0000079"})
+	if err == nil {
+		t.Error("expected an error for a limit below the current price")
+	}
+}
+
+func TestPaperMarketQuoteOrderQty(t *testing.T) {
+	ex, _ := newTestPaper()
+
+	order, err := ex.CreateOrder(OrderRequest{Symbol: "XYZBTC", Side: binance.SideTypeBuy, Type: binance.OrderTypeMarket, QuoteOrderQty: "0.001"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Status != binance.OrderStatusTypeFilled || FromS(order.ExecutedQuantity).Cmp(FromS("100")) != 0 {
+		t.Errorf("expected 100 XYZ to be bought, got %+v", order)
+	}
+	assertPaperBalance(t, ex, "BTC", "0.009", "0")
+	assertPaperBalance(t, ex, "XYZ", "1100", "0")
+
+	// more than the free balance
+	_, err = ex.CreateOrder(OrderRequest{Symbol: "XYZBTC", Side: binance.SideTypeBuy, Type: binance.OrderTypeMarket, QuoteOrderQty: "0.01"})
+	if err == nil {
+		t.Error("expected an error for an insufficient balance")
+	}
+}
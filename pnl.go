@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/adshao/go-binance/v2"
+)
+
+// lot is a bought quantity with its price, including the fees in the quote asset
+type lot struct {
+	qty   F
+	price F
+}
+
+// fifoLots matches the sells against the oldest buys, first in first out
+type fifoLots struct {
+	lots []lot
+}
+
+func (f *fifoLots) buy(qty, price F) {
+	if qty.Sign() > 0 {
+		f.lots = append(f.lots, lot{qty: qty, price: price})
+	}
+}
+
+// sell removes the quantity from the oldest lots. It returns the cost of the matched quantity
+// and the quantity, which has no matching buy.
+func (f *fifoLots) sell(qty F) (cost, unmatched F) {
+	cost = FromI(0)
+	for qty.Sign() > 0 && len(f.lots) > 0 {
+		l := &f.lots[0]
+		matched := l.qty
+		if qty.Cmp(matched) < 0 {
+			matched = qty
+		}
+		cost = cost.Add(matched.Mult(l.price))
+		qty = qty.Sub(matched)
+		l.qty = l.qty.Sub(matched)
+		if l.qty.Sign() <= 0 {
+			f.lots = f.lots[1:]
+		}
+	}
+	return cost, qty
+}
+
+// holding returns the quantity and the cost of the open lots
+func (f *fifoLots) holding() (qty, cost F) {
+	qty, cost = FromI(0), FromI(0)
+	for _, l := range f.lots {
+		qty = qty.Add(l.qty)
+		cost = cost.Add(l.qty.Mult(l.price))
+	}
+	return qty, cost
+}
+
+// trade matches a trade against the lots, with its value and the value of its fee in the same unit.
+// A fee in the base asset reduces the bought or adds to the sold quantity, its value is in the price.
+// Other fees add to the cost of a buy or reduce the proceeds of a sell.
+// It returns the cost and the gain of a sell and its quantity without a matching buy.
+func (f *fifoLots) trade(t *binance.TradeV3, baseAsset string, value, feeValue F) (cost, gain, unmatched F) {
+	qty, fee := FromS(t.Quantity), FromS(t.Commission)
+	baseFee := t.CommissionAsset == baseAsset && fee.Sign() > 0
+	if t.IsBuyer {
+		if baseFee {
+			qty = qty.Sub(fee)
+		} else {
+			value = value.Add(feeValue)
+		}
+		if qty.Sign() > 0 {
+			f.buy(qty, value.Div(qty))
+		}
+		return FromI(0), FromI(0), FromI(0)
+	}
+
+	if baseFee {
+		qty = qty.Add(fee)
+	} else {
+		value = value.Sub(feeValue)
+	}
+	cost, unmatched = f.sell(qty)
+	gain = FromI(0)
+	if matched := qty.Sub(unmatched); matched.Sign() > 0 {
+		gain = value.Mult(matched).Div(qty).Sub(cost)
+	}
+	return cost, gain, unmatched
+}
+
+// pnl is the profit and loss of the trades of one symbol, in the quote asset
+type pnl struct {
+	trades    int
+	lots      fifoLots
+	realized  F
+	unmatched F // sold quantity without a buy in the history, e.g. from deposits
+	fees      map[string]F
+	feeAssets []string
+}
+
+// computePnL matches the trades of a symbol in the order of their execution.
+// Fees in the base asset reduce the bought or add to the sold quantity, fees in the quote asset are costs.
+// Fees in other assets, e.g. BNB, are only summed up.
+func computePnL(trades []*binance.TradeV3, baseAsset, quoteAsset string) *pnl {
+	sorted := append([]*binance.TradeV3{}, trades...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Time < sorted[j].Time
+	})
+
+	result := &pnl{
+		realized:  FromI(0),
+		unmatched: FromI(0),
+		fees:      make(map[string]F),
+	}
+	for _, t := range sorted {
+		fee := FromS(t.Commission)
+		result.trades++
+		if fee.Sign() > 0 {
+			if _, exist := result.fees[t.CommissionAsset]; !exist {
+				result.fees[t.CommissionAsset] = FromI(0)
+				result.feeAssets = append(result.feeAssets, t.CommissionAsset)
+			}
+			result.fees[t.CommissionAsset] = result.fees[t.CommissionAsset].Add(fee)
+		}
+
+		feeValue := FromI(0)
+		if t.CommissionAsset == quoteAsset {
+			feeValue = fee
+		}
+		_, gain, unmatched := result.lots.trade(t, baseAsset, FromS(t.QuoteQuantity), feeValue)
+		result.realized = result.realized.Add(gain)
+		result.unmatched = result.unmatched.Add(unmatched)
+	}
+	return result
+}
+
+// unrealized returns the profit or loss of the open lots at the price
+func (p *pnl) unrealized(price F) F {
+	qty, cost := p.lots.holding()
+	return qty.Mult(price).Sub(cost)
+}
+
+// avgEntry returns the average price of the open lots
+func (p *pnl) avgEntry() F {
+	qty, cost := p.lots.holding()
+	if qty.Sign() == 0 {
+		return FromError(fmt.Errorf("no open position"))
+	}
+	return cost.Div(qty)
+}
+
+// tradePageSize is the max number of trades fetched by one request
+const tradePageSize = 1000
+
+// AllTrades fetches the trade history of the symbol starting with the trade id, page by page
+func AllTrades(exchange Exchange, symbol string, fromID int64) ([]*binance.TradeV3, error) {
+	var all []*binance.TradeV3
+	for {
+		trades, err := exchange.TradesFrom(symbol, fromID, tradePageSize)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, trades...)
+		if len(trades) < tradePageSize {
+			return all, nil
+		}
+		fromID = trades[len(trades)-1].ID + 1
+	}
+}
+
+// tradeHistory returns the complete trade history of the symbol. It is kept in the session,
+// so that only the trades after the last known one are fetched.
+func (sess *Session) tradeHistory(symbol string) ([]*binance.TradeV3, error) {
+	known := sess.trades[symbol]
+	fromID := int64(0)
+	if len(known) > 0 {
+		fromID = known[len(known)-1].ID + 1
+	}
+	trades, err := AllTrades(sess.exchange, symbol, fromID)
+	if err != nil {
+		return nil, err
+	}
+	sess.trades[symbol] = append(known, trades...)
+	return sess.trades[symbol], nil
+}
+
+// selectedPnL computes the profit and loss of the selected symbol from its trade history
+func (sess *Session) selectedPnL() (*pnl, error) {
+	trades, err := sess.tradeHistory(sess.selected)
+	if err != nil {
+		return nil, err
+	}
+	base, quote := sess.assets()
+	return computePnL(trades, base, quote), nil
+}
+
+// PnL prints the realized and unrealized profit and loss of the selected symbol
+func (sess *Session) PnL() {
+	if sess.selected == "" {
+		sess.Answer("NO SYMBOL SELECTED!")
+		return
+	}
+	p, err := sess.selectedPnL()
+	if err != nil {
+		sess.Answerf("ERROR ON FETCHING TRADES: %v", err)
+		return
+	}
+	_, quote := sess.assets()
+	current := sess.livePrice(sess.selected)
+	qty, cost := p.lots.holding()
+	unrealized := p.unrealized(current)
+
+	sess.Answerf("    trades: %v", p.trades)
+	sess.Answerf("   holding: %v", qty.StringCompact())
+	if qty.Sign() > 0 {
+		entry := p.avgEntry()
+		sess.Answerf(" avg entry: %v (%v)", entry, current.Sub(entry).Div(entry).FormatPercent())
+	}
+	sess.Answerf("  realized: %v %v / %v", p.realized, quote, sess.formatValue(p.realized.Mult(sess.quoteRate)))
+	if cost.Sign() > 0 {
+		sess.Answerf("unrealized: %v %v / %v (%v)", unrealized, quote, sess.formatValue(unrealized.Mult(sess.quoteRate)), unrealized.Div(cost).FormatPercent())
+	} else {
+		sess.Answerf("unrealized: %v %v / %v", unrealized, quote, sess.formatValue(unrealized.Mult(sess.quoteRate)))
+	}
+	var fees []string
+	for _, asset := range p.feeAssets {
+		fees = append(fees, fmt.Sprintf("%v %v", p.fees[asset].StringCompact(), asset))
+	}
+	if len(fees) == 0 {
+		fees = append(fees, "none")
+	}
+	sess.Answerf("      fees: %v", strings.Join(fees, ", "))
+	if p.unmatched.Sign() > 0 {
+		sess.Answerf("WARNING: %v sold without a buy in the trade history, not in the realized pnl", p.unmatched.StringCompact())
+	}
+}
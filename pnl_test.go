@@ -0,0 +1,106 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/adshao/go-binance/v2"
+)
+
+func testTrade(time int64, isBuyer bool, qty, quoteQty, fee, feeAsset string) *binance.TradeV3 {
+	return &binance.TradeV3{Symbol: "XYZBTC", Time: time, IsBuyer: isBuyer, Quantity: qty, QuoteQuantity: quoteQty, Commission: fee, CommissionAsset: feeAsset}
+}
+
+func TestFifoLots(t *testing.T) {
+	lots := &fifoLots{}
+	lots.buy(FromS("10"), FromS("1"))
+	lots.buy(FromS("0"), FromS("5"))
+	lots.buy(FromS("10"), FromS("2"))
+
+	tests := []struct {
+		sell      string
+		cost      string
+		unmatched string
+		holding   string
+	}{
+		{"5", "5", "0", "15"},
+		{"10", "15", "0", "5"},
+		{"8", "10", "3", "0"},
+	}
+	for _, test := range tests {
+		cost, unmatched := lots.sell(FromS(test.sell))
+		qty, _ := lots.holding()
+		if cost.Cmp(FromS(test.cost)) != 0 || unmatched.Cmp(FromS(test.unmatched)) != 0 || qty.Cmp(FromS(test.holding)) != 0 {
+			t.Errorf("sell %v: expected cost %v, unmatched %v, holding %v, got %v, %v, %v", test.sell, test.cost, test.unmatched, test.holding, cost, unmatched, qty)
+		}
+	}
+}
+
+func TestComputePnL(t *testing.T) {
+	tests := []struct {
+		name      string
+		trades    []*binance.TradeV3
+		realized  string
+		holding   string
+		cost      string
+		unmatched string
+	}{
+		{"without fees",
+			[]*binance.TradeV3{testTrade(1, true, "10", "10", "0", ""), testTrade(2, false, "5", "10", "0", "")},
+			"5", "5", "5", "0"},
+		{"sorted by time",
+			[]*binance.TradeV3{testTrade(2, false, "5", "10", "0", ""), testTrade(1, true, "10", "10", "0", "")},
+			"5", "5", "5", "0"},
+		{"buy fee in the base asset",
+			[]*binance.TradeV3{testTrade(1, true, "10", "10", "0.1", "XYZ"), testTrade(2, false, "9.9", "19.8", "0", "")},
+			"9.8", "0", "0", "0"},
+		{"fees in the quote asset",
+			[]*binance.TradeV3{testTrade(1, true, "10", "10", "0.1", "BTC"), testTrade(2, false, "10", "20", "0.2", "BTC")},
+			"9.7", "0", "0", "0"},
+		{"sell fee in the base asset",
+			[]*binance.TradeV3{testTrade(1, true, "10", "10", "0", ""), testTrade(2, false, "8", "16", "1", "XYZ")},
+			"7", "1", "1", "0"},
+		{"fees in other assets",
+			[]*binance.TradeV3{testTrade(1, true, "10", "10", "0.01", "BNB"), testTrade(2, false, "10", "20", "0.01", "BNB")},
+			"10", "0", "0", "0"},
+		{"sell without buy",
+			[]*binance.TradeV3{testTrade(1, true, "10", "10", "0", ""), testTrade(2, false, "15", "30", "0", "")},
+			"10", "0", "0", "5"},
+	}
+	for _, test := range tests {
+		p := computePnL(test.trades, "XYZ", "BTC")
+		qty, cost := p.lots.holding()
+		// the lot prices are divided, e.g. 10 / 9.9
+		if !approx([]F{p.realized, qty, cost, p.unmatched}, test.realized, test.holding, test.cost, test.unmatched) {
+			t.Errorf("%v: expected realized %v, holding %v for %v, unmatched %v, got %v, %v for %v, %v",
+				test.name, test.realized, test.holding, test.cost, test.unmatched, p.realized, qty, cost, p.unmatched)
+		}
+		if p.trades != len(test.trades) {
+			t.Errorf("%v: expected %v trades, got %v", test.name, len(test.trades), p.trades)
+		}
+	}
+
+	p := computePnL([]*binance.TradeV3{testTrade(1, true, "10", "10", "0.01", "BNB"), testTrade(2, false, "10", "20", "0.02", "BNB")}, "XYZ", "BTC")
+	if len(p.feeAssets) != 1 || p.fees["BNB"].Cmp(FromS("0.03")) != 0 {
+		t.Errorf("expected 0.03 BNB fees, got %v", p.fees)
+	}
+}
+
+func TestTradeHistory(t *testing.T) {
+	shell := newTestShell(t, nil)
+
+	// more than one page, fetched incrementally on each use
+	for i := 0; i < tradePageSize+5; i++ {
+		shell.srv.AddTrade(binance.TradeV3{Symbol: "XYZBTC", IsBuyer: true, Quantity: "1", QuoteQuantity: "0.00001"})
+	}
+	var trades []*binance.TradeV3
+	var err error
+	shell.task("trade history", func() { trades, err = shell.sess.tradeHistory("XYZBTC") })
+	if err != nil || len(trades) != tradePageSize+5 {
+		t.Fatalf("expected %v trades, got %v (%v)", tradePageSize+5, len(trades), err)
+	}
+	shell.srv.AddTrade(binance.TradeV3{Symbol: "XYZBTC", IsBuyer: false, Quantity: "1", QuoteQuantity: "0.00002"})
+	shell.task("trade history", func() { trades, err = shell.sess.tradeHistory("XYZBTC") })
+	if err != nil || len(trades) != tradePageSize+6 || trades[len(trades)-1].IsBuyer {
+		t.Fatalf("expected the new sell as last trade, got %v trades (%v)", len(trades), err)
+	}
+}
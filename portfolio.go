@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/adshao/go-binance/v2"
+)
+
+// defaultDust is the value in the reporting currency, below which positions are collapsed
+const defaultDust = 1
+
+// position is the valuation of one balance in the reporting currency
+type position struct {
+	asset  string
+	total  F
+	value  F
+	change F // 24h change of the price in the reporting currency
+}
+
+// Portfolio values all non-zero balances in the reporting currency, sorted by value.
+// Positions below the dust value are collapsed into one line, e.g. "portfolio 5".
+// The prices are taken from the 24h stats of all symbols, which are fetched once.
+func (sess *Session) Portfolio(arg string) {
+	dust := FromI(defaultDust)
+	if arg != "" {
+		dust = FromS(arg)
+		if !dust.Valid() || dust.Sign() < 0 {
+			sess.Answerf("USAGE: portfolio [dust value in %v]", sess.currency)
+			return
+		}
+	}
+
+	account, err := sess.exchange.Account()
+	if err != nil {
+		sess.Answerf("ERROR ON FETCHING ACCOUNT INFO: %v", err)
+		return
+	}
+	stats, err := sess.exchange.PriceChangeStats()
+	if err != nil {
+		sess.Answerf("ERROR ON FETCHING 24H STATS: %v", err)
+		return
+	}
+	last := statsConverter(stats, func(s *binance.PriceChangeStats) string { return s.LastPrice })
+	open := statsConverter(stats, func(s *binance.PriceChangeStats) string { return s.OpenPrice })
+	target := currencies[sess.currency]
+
+	var positions []position
+	total := FromI(0)
+	for _, b := range account.Balances {
+		amount := FromS(b.Free).Add(FromS(b.Locked))
+		if !amount.Valid() || amount.Sign() <= 0 {
+			continue
+		}
+		price := last.convert(b.Asset, target)
+		p := position{
+			asset:  b.Asset,
+			total:  amount,
+			value:  price.Mult(amount),
+			change: price.Div(open.convert(b.Asset, target)).Sub(FromI(1)),
+		}
+		if p.value.Valid() {
+			total = total.Add(p.value)
+		}
+		positions = append(positions, p)
+	}
+
+	// positions without value are listed last
+	sort.SliceStable(positions, func(i, j int) bool {
+		vi, vj := positions[i].value, positions[j].value
+		if vi.Valid() != vj.Valid() {
+			return vi.Valid()
+		}
+		return vi.Valid() && vi.Cmp(vj) > 0
+	})
+
+	sess.Answerf("%-8v %16v %14v %8v %8v", "asset", "total", sess.currency, "share", "24h "+sess.currency)
+	dustValue, dustCount := FromI(0), 0
+	for _, p := range positions {
+		if p.value.Valid() && p.value.Cmp(dust) < 0 {
+			dustValue = dustValue.Add(p.value)
+			dustCount++
+			continue
+		}
+		if !p.value.Valid() {
+			sess.Answerf("%-8v %16v %v", p.asset, p.total.StringCompact(), p.value)
+			continue
+		}
+		change := ""
+		if p.change.Valid() {
+			change = p.change.FormatPercent()
+		}
+		sess.Answerf("%-8v %16v %14v %8v %8v", p.asset, p.total.StringCompact(), sess.formatValue(p.value), share(p.value, total), change)
+	}
+	if dustCount > 0 {
+		sess.Answerf("%-8v %16v %14v %8v", "dust", fmt.Sprintf("%v assets", dustCount), sess.formatValue(dustValue), share(dustValue, total))
+	}
+	sess.Answerf("%-8v %16v %14v", "total", "", sess.formatValue(total))
+}
+
+// share returns the part of the total as percent, or an empty string for an empty total
+func share(value, total F) string {
+	if total.Sign() == 0 {
+		return ""
+	}
+	return value.Div(total).FormatPercent()
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/adshao/go-binance/v2"
+	"github.com/smancke/trading-shell/binancefake"
+	"github.com/smancke/trading-shell/config"
+)
+
+func TestPortfolio(t *testing.T) {
+	shell := newTestShell(t, func(srv *binancefake.Server, c *config.Config) {
+		srv.SetBalance("XYZ", "1500", "500")
+		srv.SetBalance("EUR", "0.5", "0")
+		srv.SetBalance("ABC", "5", "0")
+		srv.SetStats(binance.PriceChangeStats{Symbol: "XYZBTC", OpenPrice: "0.000008", LastPrice: "0.00001", PriceChangePercent: "25"})
+		srv.SetStats(binance.PriceChangeStats{Symbol: "BTCEUR", OpenPrice: "40000", LastPrice: "50000", PriceChangePercent: "25"})
+	})
+
+	lines := strings.Split(shell.run("portfolio"), "\n")
+	var rows []string
+	for _, line := range lines {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			rows = append(rows, strings.Join(fields, " "))
+		}
+	}
+	// sorted by value, the dust collapsed and the unknown value last.
+	// The change of XYZ is converted to EUR: 0.000008 * 40000 -> 0.00001 * 50000
+	expected := []string{
+		"-------- portfolio -------",
+		"asset total EUR share 24h EUR",
+		"XYZ 2000 1000.00€ 66.64% 56.25%",
+		"BTC 0.01 500.00€ 33.32% 25.00%",
+		"ABC 5 no conversion from ABC to EUR",
+		"dust 1 assets 0.50€ 0.03%",
+		"total 1500.50€",
+	}
+	if !equalStrings(rows, expected) {
+		t.Errorf("expected:\n%v\ngot:\n%v", strings.Join(expected, "\n"), strings.Join(rows, "\n"))
+	}
+
+	if out := shell.run("portfolio -1"); !strings.Contains(out, "USAGE: portfolio") {
+		t.Errorf("expected the usage, got:\n%v", out)
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
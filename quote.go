@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/adshao/go-binance/v2"
+)
+
+// quoteAssets are the supported quote assets, in the order they are tried,
+// if a symbol is given by its base asset only
+var quoteAssets = []string{"BTC", "USDT", "BUSD", "ETH", "BNB", "EUR"}
+
+// isQuoteAsset returns true, if the asset is one of the supported quote assets
+func isQuoteAsset(asset string) bool {
+	for _, quote := range quoteAssets {
+		if quote == asset {
+			return true
+		}
+	}
+	return false
+}
+
+// quotes returns the quote assets with the preferred quote first
+func (sess *Session) quotes() []string {
+	quotes := []string{sess.quote}
+	for _, quote := range quoteAssets {
+		if quote != sess.quote {
+			quotes = append(quotes, quote)
+		}
+	}
+	return quotes
+}
+
+// setQuote changes the preferred quote asset, e.g. "USDT"
+func (sess *Session) setQuote(value string) error {
+	value = strings.ToUpper(value)
+	if !isQuoteAsset(value) {
+		return fmt.Errorf("quote has to be one of %v: %q", strings.Join(quoteAssets, ", "), value)
+	}
+	sess.quote = value
+	return nil
+}
+
+// findSymbol returns the symbol for the name, which is either a symbol
+// or a base asset, traded against one of the quotes
+func (sess *Session) findSymbol(name string) (*binance.PriceChangeStats, bool) {
+	if stats, exist := sess.allPriceStats[name]; exist {
+		return stats, true
+	}
+	for _, quote := range sess.quotes() {
+		if stats, exist := sess.allPriceStats[name+quote]; exist {
+			return stats, true
+		}
+	}
+	return nil, false
+}
+
+// market returns the symbol to trade the asset against one of the quotes, or nil
+func (sess *Session) market(asset string) *binance.Symbol {
+	for _, quote := range sess.quotes() {
+		if symbol := sess.allSymbols[asset+quote]; symbol != nil && quote != asset {
+			return symbol
+		}
+	}
+	return nil
+}
+
+// assets returns the base and quote asset of the selected symbol.
+// Without exchange info, the quote is derived from the suffix of the symbol.
+func (sess *Session) assets() (base, quote string) {
+	if symbol := sess.allSymbols[sess.selected]; symbol != nil {
+		return symbol.BaseAsset, symbol.QuoteAsset
+	}
+	for _, quote := range sess.quotes() {
+		if strings.HasSuffix(sess.selected, quote) && len(sess.selected) > len(quote) {
+			return strings.TrimSuffix(sess.selected, quote), quote
+		}
+	}
+	return sess.selected, ""
+}
+
+// balance returns the balance of the base asset of the selected symbol
+func (sess *Session) balance() (free F, locked F) {
+	base, _ := sess.assets()
+	return Balance(sess.exchange, base)
+}
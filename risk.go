@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/adshao/go-binance/v2"
+	"github.com/smancke/trading-shell/config"
+)
+
+// riskLimits are checked before any order is sent to the exchange.
+// A zero limit is not checked. Values are in the reporting currency.
+type riskLimits struct {
+	maxOrder      F
+	maxDaily      F // max value of the buys placed per day
+	maxOpenOrders int
+	maxDeviation  F               // max distance of the limit price from the current price
+	blacklist     map[string]bool // symbols and assets, which are not traded
+}
+
+func newRiskLimits(config *config.Config) riskLimits {
+	limits := riskLimits{
+		maxOrder:      FromI(config.RiskMaxOrder),
+		maxDaily:      FromI(config.RiskMaxDaily),
+		maxOpenOrders: config.RiskMaxOpenOrders,
+		maxDeviation:  FromI(config.RiskMaxDeviation).Div(FromI(100)),
+		blacklist:     make(map[string]bool),
+	}
+	for _, s := range strings.Split(config.RiskBlacklist, ",") {
+		if s = strings.ToUpper(strings.TrimSpace(s)); s != "" {
+			limits.blacklist[s] = true
+		}
+	}
+	return limits
+}
+
+// dailyVolume is the value of the buys placed on one day, in the reporting currency
+type dailyVolume struct {
+	day   string // the local date, e.g. 2021-05-01
+	value F
+}
+
+// today returns the volume of the current day
+func (v dailyVolume) today() F {
+	if v.day != time.Now().Format("2006-01-02") {
+		return FromI(0)
+	}
+	return v.value
+}
+
+// checkRisk returns an error, if the orders would violate a risk limit.
+// The orders are checked together, as they are sent by one command. If they replace
+// the open orders of the selected symbol, these are not counted for the max of open orders.
+// The exits of the trailing stop and the kill switch are only checked against the blacklist,
+// so that they are never blocked by the limits.
+func (sess *Session) checkRisk(replacing bool, previews ...orderPreview) error {
+	limits := sess.risk
+	total := FromI(0)
+	resting := 0
+	for _, p := range previews {
+		if limits.blacklist[p.symbol] {
+			return fmt.Errorf("%v is blacklisted", p.symbol)
+		}
+		if symbol := sess.allSymbols[p.symbol]; symbol != nil && (limits.blacklist[symbol.BaseAsset] || limits.blacklist[symbol.QuoteAsset]) {
+			return fmt.Errorf("%v is blacklisted", p.symbol)
+		}
+		if sess.exiting {
+			continue
+		}
+
+		value := sess.previewValue(p)
+		if !value.Valid() {
+			return fmt.Errorf("order value unknown: %v", value)
+		}
+		if limits.maxOrder.Sign() > 0 && value.Cmp(limits.maxOrder) > 0 {
+			return fmt.Errorf("order value of %v is above the max of %v per order", sess.formatValue(value), sess.formatValue(limits.maxOrder))
+		}
+		if p.side == binance.SideTypeBuy {
+			total = total.Add(value)
+		}
+		if p.orderType != binance.OrderTypeMarket {
+			resting++
+		}
+
+		if limits.maxDeviation.Sign() > 0 {
+			if !p.distance.Valid() {
+				return fmt.Errorf("distance from the current price unknown: %v", p.distance)
+			}
+			deviation := p.distance
+			if deviation.Sign() < 0 {
+				deviation = deviation.Mult(FromI(-1))
+			}
+			if deviation.Cmp(limits.maxDeviation) > 0 {
+				return fmt.Errorf("price %v is %v away from the current price, max is %v", p.price, deviation.FormatPercent(), limits.maxDeviation.FormatPercent())
+			}
+		}
+	}
+	if sess.exiting {
+		return nil
+	}
+
+	if daily := sess.daily.today().Add(total); limits.maxDaily.Sign() > 0 && total.Sign() > 0 && daily.Cmp(limits.maxDaily) > 0 {
+		return fmt.Errorf("daily volume would be %v, max is %v", sess.formatValue(daily), sess.formatValue(limits.maxDaily))
+	}
+
+	if limits.maxOpenOrders > 0 && resting > 0 {
+		orders, err := sess.exchange.OpenOrders("")
+		if err != nil {
+			return fmt.Errorf("could not count the open orders: %v", err)
+		}
+		open := 0
+		for _, o := range orders {
+			if !replacing || o.Symbol != sess.selected {
+				open++
+			}
+		}
+		if open+resting > limits.maxOpenOrders {
+			return fmt.Errorf("%v open orders and %v new ones are above the max of %v", open, resting, limits.maxOpenOrders)
+		}
+	}
+	return nil
+}
+
+// recordOrder adds the value of a placed buy to the daily volume
+func (sess *Session) recordOrder(p orderPreview) {
+	if p.side != binance.SideTypeBuy {
+		return
+	}
+	value := sess.previewValue(p)
+	if !value.Valid() {
+		sess.Answerf("WARNING: value of the %v buy not in the daily volume: %v", p.symbol, value)
+		return
+	}
+	today := time.Now().Format("2006-01-02")
+	if sess.daily.day != today {
+		sess.daily = dailyVolume{day: today, value: FromI(0)}
+	}
+	sess.daily.value = sess.daily.value.Add(value)
+	sess.saveState()
+}
+
+// ShowRisk prints the risk limits and the volume of today
+func (sess *Session) ShowRisk() {
+	limits := sess.risk
+	sess.Answerf("  max order: %v", sess.formatValue(limits.maxOrder))
+	sess.Answerf("  max daily: %v (buys placed today %v)", sess.formatValue(limits.maxDaily), sess.formatValue(sess.daily.today()))
+	sess.Answerf("open orders: %v", limits.maxOpenOrders)
+	sess.Answerf("  deviation: %v", limits.maxDeviation.FormatPercent())
+	var blacklist []string
+	for s := range limits.blacklist {
+		blacklist = append(blacklist, s)
+	}
+	sort.Strings(blacklist)
+	sess.Answerf("  blacklist: %v", strings.Join(blacklist, ","))
+	sess.Answer("(a limit of 0 is not checked)")
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/smancke/trading-shell/binancefake"
+	"github.com/smancke/trading-shell/config"
+)
+
+func TestDailyVolumeCountsPlacedBuys(t *testing.T) {
+	shell := newTestShell(t, func(srv *binancefake.Server, c *config.Config) {
+		srv.SetBalance("XYZ", "1000", "0")
+		c.ConfirmAbove = 0
+		c.ConfirmDistance = 0
+	})
+	shell.run("xyzbtc")
+
+	// a resting buy counts with its value, 200 XYZ at 0.000005 BTC are 50€
+	shell.run("buy 0.5")
+	if out := shell.run("risk"); !strings.Contains(out, "buys placed today 50.00€") {
+		t.Errorf("expected the resting buy in the daily volume, got:\n%v", out)
+	}
+
+	// sells are not counted
+	shell.run("stop 0.5")
+	shell.run("market-buy")
+	shell.run("oco 2 0.5")
+	if out := shell.run("risk"); !strings.Contains(out, "buys placed today 100.00€") {
+		t.Errorf("expected only the buys in the daily volume, got:\n%v", out)
+	}
+}
+
+func TestRiskLimitsApplyToSells(t *testing.T) {
+	shell := newTestShell(t, func(srv *binancefake.Server, c *config.Config) {
+		srv.SetBalance("XYZ", "1000", "0")
+		c.ConfirmAbove = 0
+		c.ConfirmDistance = 0
+		c.RiskMaxOrder = 100
+		c.RiskMaxOpenOrders = 4
+		c.RiskMaxDeviation = 50
+	})
+	shell.run("xyzbtc")
+
+	// 1000 XYZ at 0.00001 BTC are 500€
+	if out := shell.run("market-sell"); !strings.Contains(out, "ORDER BLOCKED BY RISK LIMIT: order value of 500.00€ is above the max of 100.00€ per order") {
+		t.Errorf("expected the sell to be blocked by the max order, got:\n%v", out)
+	}
+	if out := shell.run("sell 0.1"); !strings.Contains(out, "ORDER BLOCKED BY RISK LIMIT: price 0.00000100 is 90.00% away from the current price") {
+		t.Errorf("expected the sell to be blocked by the deviation, got:\n%v", out)
+	}
+
+	// the wall is replaced, although it uses all open orders
+	shell.srv.SetBalance("XYZ", "100", "0")
+	shell.run("sell-wall 1.5")
+	if out := shell.run("sell-wall 1.4"); strings.Contains(out, "BLOCKED") || len(shell.openOrders()) != 4 {
+		t.Errorf("expected the sell wall to be replaced, got:\n%v", out)
+	}
+	if out := shell.run("buy 0.9"); !strings.Contains(out, "ORDER BLOCKED BY RISK LIMIT: 4 open orders and 1 new ones are above the max of 4") {
+		t.Errorf("expected the buy to be blocked by the open orders, got:\n%v", out)
+	}
+	if len(shell.srv.Orders()) != 8 {
+		t.Errorf("expected only the orders of both walls, got %+v", shell.srv.Orders())
+	}
+
+	// the exit of a trailing stop is not blocked
+	trailing := &trailingStop{symbol: "XYZBTC", percent: FromI(5), stop: make(chan struct{})}
+	if out := shell.trailExit(trailing, "0.000001"); strings.Contains(out, "BLOCKED") {
+		t.Errorf("expected the exit not to be blocked, got:\n%v", out)
+	}
+	if orders := shell.srv.Orders(); orders[len(orders)-1].Price != "0.00000100" {
+		t.Errorf("expected the exit sell at 0.00000100, got %+v", orders[len(orders)-1])
+	}
+}
+
+func TestUnknownValuesAreBlocked(t *testing.T) {
+	shell := newTestShell(t, func(srv *binancefake.Server, c *config.Config) {
+		c.RiskMaxDeviation = 50
+	})
+	shell.run("xyzbtc")
+
+	for i, p := range unknownPreviews() {
+		var err error
+		shell.task("check risk", func() { err = shell.sess.checkRisk(false, p) })
+		if err == nil || !strings.Contains(err.Error(), "unknown") {
+			t.Errorf("preview %v: expected the order to be blocked, got %v", i, err)
+		}
+	}
+}
+
+func TestRiskBlacklist(t *testing.T) {
+	shell := newTestShell(t, func(srv *binancefake.Server, c *config.Config) {
+		srv.SetBalance("XYZ", "1000", "0")
+		c.RiskBlacklist = "xyz"
+	})
+	shell.run("xyzbtc")
+	for _, cmd := range []string{"buy 1", "sell-wall"} {
+		if out := shell.run(cmd); !strings.Contains(out, "ORDER BLOCKED BY RISK LIMIT: XYZBTC is blacklisted") {
+			t.Errorf("%v: expected the order to be blocked, got:\n%v", cmd, out)
+		}
+	}
+	if len(shell.srv.Orders()) != 0 {
+		t.Errorf("expected no orders, got %+v", shell.srv.Orders())
+	}
+}
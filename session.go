@@ -1,7 +1,6 @@
 package main
 
 import (
-	"context"
 	"fmt"
 	"github.com/adshao/go-binance/v2"
 	"github.com/smancke/trading-shell/config"
@@ -10,45 +9,74 @@ import (
 )
 
 type Session struct {
-	client        *binance.Client
+	exchange      Exchange
 	in            chan string
 	out           chan string
+	tasks         chan func() // work from background routines, executed by the dispatcher
 	allPriceStats map[string]*binance.PriceChangeStats
 	allSymbols    map[string]*binance.Symbol
 	selected      string // the selected symbol
-	btcPrice      F
-	avg24h        F // average for the last 24 hours
-	avgRecent     F // average for the last recent time (e.g. 5 min)
-	basePrice     F // the base price for limit calculations
-	maxInvestEUR  F // max volume for the trading
-	buyMaxMult    F // multiplier for the hightest buy limit, relative to the basePrice
-	sellMaxMult   F // multiplier for the hightest sell limit, relative to the basePrice
-	sellMinMult   F // multiplier for the lowest sell limt to exit, relative to the basePrice
+	quoteRate     F      // value of one unit of the quote asset in the reporting currency
+	avg24h        F      // average for the last 24 hours
+	avgRecent     F      // average for the last recent time (e.g. 5 min)
+	basePrice     F      // the base price for limit calculations
+	maxInvest     F      // max volume for the trading, in the reporting currency
+	buyMaxMult    F      // multiplier for the hightest buy limit, relative to the basePrice
+	sellMaxMult   F      // multiplier for the hightest sell limit, relative to the basePrice
+	sellMinMult   F      // multiplier for the lowest sell limt to exit, relative to the basePrice
+	stateFile     string // file to persist the session parameters, empty for no persistence
+	quote         string // preferred quote asset, if a symbol is selected by its base asset
+	currency      string // reporting currency of all values, the invest amount and the limits
+	wallShape     wallShape
+	trailing      *trailingStop
+	streaming     bool        // use the websocket stream for live prices
+	live          *livePrices // prices received over the stream
+	watching      *watcher
+	dryRun        bool // only print the orders, instead of sending them
+	confirmed     bool // the user confirmed the orders of the current command
+	exiting       bool // the orders of the current command are exits of the trailing stop or the kill switch
+	confirmAbove  F    // value of an order, which needs a confirmation
+	confirmDist   F    // distance from the current price, which needs a confirmation
+	risk          riskLimits
+	daily         dailyVolume
+	trades        map[string][]*binance.TradeV3 // the trade history by symbol, extended by the newer trades on each use
+	journal       *journal
+	command       string // the input line of the running command, for the journal
+	klineCache    string // directory to cache the klines of the backtests
 }
 
-func StartSession(config *config.Config) *Session {
+func StartSession(exchange Exchange, config *config.Config) *Session {
 	sess := &Session{
 		allPriceStats: make(map[string]*binance.PriceChangeStats),
 		allSymbols:    make(map[string]*binance.Symbol),
-		client:        binance.NewClient(config.APIKey, config.APISecret),
+		exchange:      exchange,
 		in:            make(chan string, 1),
 		out:           make(chan string, 1),
-		maxInvestEUR:  FromF(50.0),
-		buyMaxMult:    FromF(1.2),
-		sellMaxMult:   FromF(4.5),
-		sellMinMult:   FromF(1),
+		tasks:         make(chan func()),
+		stateFile:     config.StateFile,
+		currency:      strings.ToUpper(config.Currency),
+		streaming:     config.Stream,
+		live:          newLivePrices(),
+		dryRun:        config.DryRun,
+		confirmAbove:  FromI(config.ConfirmAbove),
+		confirmDist:   FromI(config.ConfirmDistance).Div(FromI(100)),
+		risk:          newRiskLimits(config),
+		trades:        make(map[string][]*binance.TradeV3),
+		journal:       newJournal(config.Journal),
+		klineCache:    config.BacktestCache,
 	}
+	sess.setDefaults()
 
-	ex, err := sess.client.NewExchangeInfoService().Do(context.Background())
+	ex, err := sess.exchange.ExchangeInfo()
 	if err != nil {
 		sess.Answer(err.Error())
 	} else {
-		for _, symbol := range ex.Symbols {
-			sess.allSymbols[symbol.Symbol] = &symbol
+		for i := range ex.Symbols {
+			sess.allSymbols[ex.Symbols[i].Symbol] = &ex.Symbols[i]
 		}
 	}
 
-	stats, err := sess.client.NewListPriceChangeStatsService().Do(context.Background())
+	stats, err := sess.exchange.PriceChangeStats()
 	if err != nil {
 		sess.Answer(err.Error())
 	} else {
@@ -80,29 +108,115 @@ func (sess *Session) Answerf(format string, a ...interface{}) {
 }
 
 func (sess *Session) ShowConfig() {
-	sess.Answerf(`   Invest: %v EUR
-Buy limit: %v
- Sell Max: %v
- Sell Min: %v
-`, sess.maxInvestEUR.StringCompact(), sess.buyMaxMult.FormatPercent(), sess.sellMaxMult.FormatPercent(), sess.sellMinMult.FormatPercent())
+	sess.ShowSettings()
+	sess.ShowBalances()
+}
 
-	account, err := sess.client.NewGetAccountService().Do(context.Background())
+// ShowBalances prints the non-zero balances of the account
+func (sess *Session) ShowBalances() {
+	account, err := sess.exchange.Account()
 	if err != nil {
 		sess.Answerf("ERROR ON FETCHING ACCOUNT INFO: %v", err)
+		return
 	}
 	for _, b := range account.Balances {
 		total := FromS(b.Free).Add(FromS(b.Locked))
 		if b.Asset == "BTC" {
-			eur := BTCEURPrice(sess.client).Mult(total).FormatEUR()
-			sess.Answerf(" %v: %v / %v", b.Asset, total, eur)
+			value := sess.formatValue(sess.currencyPrice(b.Asset).Mult(total))
+			sess.Answerf(" %v: %v / %v", b.Asset, total, value)
 		} else {
-			if total.V > 0 {
+			if total.Sign() > 0 {
 				sess.Answerf(" %v: %v", b.Asset, total)
 			}
 		}
 	}
 }
 
+func (sess *Session) ShowSettings() {
+	sess.Answerf(`   Invest: %v %v
+Buy limit: %v
+ Sell Max: %v
+ Sell Min: %v
+Sell wall: %v
+    Quote: %v
+  Confirm: above %v or %v from price`, sess.maxInvest.StringCompact(), sess.currency, sess.buyMaxMult.FormatPercent(), sess.sellMaxMult.FormatPercent(), sess.sellMinMult.FormatPercent(), sess.wallShape, sess.quote, sess.formatValue(sess.confirmAbove), sess.confirmDist.FormatPercent())
+	if sess.dryRun {
+		sess.Answer("  DRY RUN: orders are only printed")
+	}
+	if sess.selected != "" {
+		sess.Answerf("basePrice: %v (%v)", sess.basePrice, sess.selected)
+	}
+	sess.Answer("")
+}
+
+// Set changes a session parameter, e.g. "set invest 100"
+func (sess *Session) Set(arg string) {
+	fields := strings.Fields(arg)
+	if len(fields) != 2 {
+		sess.Answer("USAGE: set <invest|buy|sell-max|sell-min|base|steps|spacing|distribution|quote> <value>")
+		return
+	}
+
+	switch param := strings.ToLower(fields[0]); param {
+	case "steps", "spacing", "distribution":
+		if err := sess.wallShape.setParam(param, fields[1]); err != nil {
+			sess.Answerf("INVALID VALUE: %v", err)
+			return
+		}
+		sess.saveState()
+		sess.ShowSettings()
+		return
+	case "quote":
+		if err := sess.setQuote(fields[1]); err != nil {
+			sess.Answerf("INVALID VALUE: %v", err)
+			return
+		}
+		sess.saveState()
+		sess.ShowSettings()
+		return
+	}
+
+	value := FromS(fields[1])
+	if !value.Valid() {
+		sess.Answerf("NOT A NUMBER: %q", fields[1])
+		return
+	}
+	if value.Sign() <= 0 {
+		sess.Answerf("VALUE HAS TO BE POSITIVE: %v", fields[1])
+		return
+	}
+
+	switch strings.ToLower(fields[0]) {
+	case "invest", "maxinvest", "maxinvesteur":
+		sess.maxInvest = value
+	case "buy", "buymaxmult":
+		sess.buyMaxMult = value
+	case "sell-max", "sellmaxmult":
+		if value.Cmp(sess.sellMinMult) <= 0 {
+			sess.Answerf("SELL MAX HAS TO BE ABOVE SELL MIN OF %v", sess.sellMinMult.StringCompact())
+			return
+		}
+		sess.sellMaxMult = value
+	case "sell-min", "sellminmult":
+		if value.Cmp(sess.sellMaxMult) >= 0 {
+			sess.Answerf("SELL MIN HAS TO BE BELOW SELL MAX OF %v", sess.sellMaxMult.StringCompact())
+			return
+		}
+		sess.sellMinMult = value
+	case "base", "baseprice":
+		if sess.selected == "" {
+			sess.Answer("NO SYMBOL SELECTED!")
+			return
+		}
+		sess.basePrice = value
+	default:
+		sess.Answerf("UNKNOWN PARAMETER: %q", fields[0])
+		return
+	}
+	sess.saveState()
+	sess.ShowSettings()
+}
+
 func (sess *Session) SymbolInfo() {
 	symbolInfo := sess.allSymbols[sess.selected]
 	sess.Answerf("symbol info %+v", symbolInfo)
@@ -110,27 +224,39 @@ func (sess *Session) SymbolInfo() {
 
 func (sess *Session) Init(symbol string) {
 	if symbol != "" {
-		symbol = strings.ToUpper(symbol)
-		stats, exist := sess.allPriceStats[symbol]
-		if !exist {
-			stats, exist = sess.allPriceStats[symbol+"BTC"]
-			if !exist {
-				sess.Answerf("SYMBOL NOT FOUND: %q", symbol)
-				return
-			}
-		}
-		sess.selected = stats.Symbol
-		sess.avgRecent = AvgPrice(sess.client, sess.selected)
-		sess.basePrice = sess.avgRecent
-		sess.avg24h = FromS(stats.WeightedAvgPrice)
-		sess.btcPrice = BTCEURPrice(sess.client)
-		if !sess.btcPrice.Valid() {
-			sess.Answerf("ERROR ON BTC PRICE UPDATE: %v", sess.btcPrice)
+		if !sess.selectSymbol(symbol) {
+			return
 		}
+		sess.saveState()
 	}
 	sess.Info()
 }
 
+// selectSymbol makes the symbol the selected one and sets the basePrice to the recent average.
+// A base asset selects its market to the preferred quote, or to the first other quote.
+func (sess *Session) selectSymbol(symbol string) bool {
+	symbol = strings.ToUpper(symbol)
+	stats, exist := sess.findSymbol(symbol)
+	if !exist {
+		sess.Answerf("SYMBOL NOT FOUND: %q", symbol)
+		return false
+	}
+	if sess.trailing != nil && sess.trailing.symbol != stats.Symbol {
+		sess.stopTrailing()
+	}
+	sess.selected = stats.Symbol
+	sess.subscribePrices()
+	sess.avgRecent = AvgPrice(sess.exchange, sess.selected)
+	sess.basePrice = sess.avgRecent
+	sess.avg24h = FromS(stats.WeightedAvgPrice)
+	_, quote := sess.assets()
+	sess.quoteRate = sess.currencyPrice(quote)
+	if !sess.quoteRate.Valid() {
+		sess.Answerf("ERROR ON %v PRICE UPDATE: %v", quote, sess.quoteRate)
+	}
+	return true
+}
+
 func (sess *Session) Buy(multS string) {
 	if sess.selected == "" {
 		sess.Answer("NO SYMBOL SELECTED!")
@@ -142,148 +268,394 @@ func (sess *Session) Buy(multS string) {
 		mult = FromS(multS)
 	}
 
-	limit := sess.basePrice.Mult(mult)
-	limitS := sess.basePrice.Mult(mult).StringPrice()
-	qty := sess.maxInvestEUR.Div(sess.btcPrice).Div(limit).Floor().StringPrice()
+	qty, limit := buyLimit(sess.basePrice, mult, sess.maxInvest.Div(sess.quoteRate))
+	sess.placeLimitOrder(binance.SideTypeBuy, qty, limit)
+}
+
+// buyLimit returns the quantity and limit of a buy for the quote amount at the multiplier of the basePrice
+func buyLimit(basePrice, mult, quoteQty F) (qty, limit F) {
+	limit = basePrice.Mult(mult)
+	return quoteQty.Div(limit), limit
+}
+
+// filters returns the trading rules of the selected symbol
+func (sess *Session) filters() SymbolFilters {
+	return NewSymbolFilters(sess.allSymbols[sess.selected])
+}
+
+// placeLimitOrder places a GTC limit order, see placeOrder
+func (sess *Session) placeLimitOrder(side binance.SideType, qty F, limit F) bool {
+	return sess.placeOrder(binance.OrderTypeLimit, side, qty, limit, F{})
+}
+
+// placeOrder rounds quantity and prices to the symbol filters and places a GTC order.
+// The stop price is only used for stop loss and take profit orders.
+// It returns false, if the order could not be placed.
+func (sess *Session) placeOrder(orderType binance.OrderType, side binance.SideType, qty, limit, stop F) bool {
+	filters := sess.filters()
+	qty = filters.Quantity(qty)
+	limit = filters.Price(limit, side)
+	if err := filters.Check(qty, limit); err != nil {
+		sess.Answerf("ORDER NOT POSSIBLE FOR %v of %v: %v", qty.StringCompact(), sess.selected, err)
+		return false
+	}
 
-	//sess.Answerf("BUY %v @%v", qty, limit)
-	order, err := sess.client.NewCreateOrderService().Symbol(sess.selected).
-		Side(binance.SideTypeBuy).Type(binance.OrderTypeLimit).
-		TimeInForce(binance.TimeInForceTypeGTC).Quantity(qty).
-		Price(limitS).Do(context.Background())
+	req := OrderRequest{
+		Symbol:      sess.selected,
+		Side:        side,
+		Type:        orderType,
+		TimeInForce: binance.TimeInForceTypeGTC,
+		Quantity:    qty.String(),
+		Price:       limit.String(),
+	}
+	if stop.Sign() != 0 {
+		stop = filters.Price(stop, side)
+		if err := filters.Check(qty, stop); err != nil {
+			sess.Answerf("ORDER NOT POSSIBLE FOR %v of %v: %v", qty.StringCompact(), sess.selected, err)
+			return false
+		}
+		req.StopPrice = stop.String()
+	}
+
+	preview := sess.preview(side, orderType, qty, limit)
+	preview.stop = req.StopPrice != ""
+	if !sess.approve(preview) {
+		return false
+	}
+
+	order, err := sess.exchange.CreateOrder(req)
 	if err != nil {
-		sess.Answerf("ERROR ON ORDER FOR %v of %v: %v", qty, sess.selected, err)
-		return
+		sess.Answerf("ERROR ON %v ORDER FOR %v of %v: %v", side, qty.StringCompact(), sess.selected, err)
+		return false
 	}
+	sess.recordOrder(preview)
+	sess.journalOrder(order.Symbol, order.Side, order.Type, order.OrderID, order.OrigQuantity, order.Price, req.StopPrice, order.Status)
 	if order.Status == binance.OrderStatusTypeRejected {
 		sess.Answerf("ORDER REJECTED!!!!")
 	}
-	sess.Answerf("%v [%v of %v@%v (%v%% executed, %v)]", order.Side, order.OrigQuantity, order.Symbol, order.Price, FromS(order.ExecutedQuantity).Div(FromS(order.OrigQuantity)).FormatPercent(), order.Status)
+	trigger := ""
+	if req.StopPrice != "" {
+		trigger = fmt.Sprintf(" %v trigger@%v", order.Type, req.StopPrice)
+	}
+	sess.Answerf("%v [%v of %v@%v%v (%v executed, %v)]", order.Side, order.OrigQuantity, order.Symbol, order.Price, trigger, FromS(order.ExecutedQuantity).Div(FromS(order.OrigQuantity)).FormatPercent(), order.Status)
+	return true
 }
 
-func (sess *Session) CancelAllOrders() {
-	orders, err := sess.client.NewListOpenOrdersService().Symbol(sess.selected).Do(context.Background())
-	if len(orders) > 0 || err != nil {
-		_, err := sess.client.NewCancelOpenOrdersService().Symbol(sess.selected).Do(context.Background())
-		if err != nil {
-			sess.Answerf("ERROR ON CANCEL ORDERS %v", err)
-			return
-		}
-		time.Sleep(time.Millisecond * 200)
+// StopOrder places a stop loss or take profit limit order for the free balance.
+// The arguments are the multipliers of the basePrice for the trigger and optional for the limit,
+// e.g. "0.9" or "0.9 0.88". Without limit multiplier, the limit is the trigger price.
+func (sess *Session) StopOrder(orderType binance.OrderType, arg string) {
+	if sess.selected == "" {
+		sess.Answer("NO SYMBOL SELECTED!")
+		return
+	}
+
+	args := strings.Fields(arg)
+	if len(args) < 1 || len(args) > 2 {
+		sess.Answer("USAGE: <stop|take-profit> <trigger multiplier> [limit multiplier]")
+		return
+	}
+	stopMult := FromS(args[0])
+	limitMult := stopMult
+	if len(args) > 1 {
+		limitMult = FromS(args[1])
+	}
+	if !stopMult.Valid() || !limitMult.Valid() || stopMult.Sign() <= 0 || limitMult.Sign() <= 0 {
+		sess.Answerf("INVALID MULTIPLIER: %q", arg)
+		return
 	}
+
+	free, locked := sess.balance()
+	if locked.Sign() != 0 {
+		sess.Answerf("WARNING: locked balance of %v not in order!", locked)
+	}
+
+	stop := sess.basePrice.Mult(stopMult)
+	limit := sess.basePrice.Mult(limitMult)
+	sess.placeOrder(orderType, binance.SideTypeSell, free, limit, stop)
 }
 
-func (sess *Session) SellAllNow(multS string) {
+// Oco places a one-cancels-the-other sell for the free balance, consisting of a take profit limit
+// and a stop loss limit. The arguments are multipliers of the basePrice,
+// e.g. "2 0.9" or "2 0.9 0.88". Without stop limit multiplier, the stop limit is the trigger price.
+func (sess *Session) Oco(arg string) {
 	if sess.selected == "" {
 		sess.Answer("NO SYMBOL SELECTED!")
 		return
 	}
 
-	sess.CancelAllOrders()
-
-	mult := sess.sellMinMult
-	if multS != "" {
-		mult = FromS(multS)
+	args := strings.Fields(arg)
+	if len(args) < 2 || len(args) > 3 {
+		sess.Answer("USAGE: oco <take profit multiplier> <stop multiplier> [stop limit multiplier]")
+		return
+	}
+	profitMult := FromS(args[0])
+	stopMult := FromS(args[1])
+	stopLimitMult := stopMult
+	if len(args) > 2 {
+		stopLimitMult = FromS(args[2])
+	}
+	for _, mult := range []F{profitMult, stopMult, stopLimitMult} {
+		if !mult.Valid() || mult.Sign() <= 0 {
+			sess.Answerf("INVALID MULTIPLIER: %q", arg)
+			return
+		}
 	}
 
-	limit := sess.basePrice.Mult(mult)
-	free, locked := Balance(sess.client, sess.selected)
-	if locked.V != 0 {
+	free, locked := sess.balance()
+	if locked.Sign() != 0 {
 		sess.Answerf("WARNING: locked balance of %v not in order!", locked)
 	}
 
-	order, err := sess.client.NewCreateOrderService().Symbol(sess.selected).
-		Side(binance.SideTypeSell).Type(binance.OrderTypeLimit).
-		TimeInForce(binance.TimeInForceTypeGTC).Quantity(free.Floor().String()).
-		Price(limit.String()).Do(context.Background())
+	filters := sess.filters()
+	qty := filters.Quantity(free)
+	price := filters.Price(sess.basePrice.Mult(profitMult), binance.SideTypeSell)
+	stop := filters.Price(sess.basePrice.Mult(stopMult), binance.SideTypeSell)
+	stopLimit := filters.Price(sess.basePrice.Mult(stopLimitMult), binance.SideTypeSell)
+	for _, p := range []F{price, stop, stopLimit} {
+		if err := filters.Check(qty, p); err != nil {
+			sess.Answerf("ORDER NOT POSSIBLE FOR %v of %v: %v", qty.StringCompact(), sess.selected, err)
+			return
+		}
+	}
+
+	limitPreview := sess.preview(binance.SideTypeSell, binance.OrderTypeLimitMaker, qty, price)
+	stopPreview := sess.preview(binance.SideTypeSell, binance.OrderTypeStopLossLimit, qty, stopLimit)
+	stopPreview.stop = true
+	if !sess.approve(limitPreview, stopPreview) {
+		return
+	}
+
+	list, err := sess.exchange.CreateOCO(OCORequest{
+		Symbol:         sess.selected,
+		Side:           binance.SideTypeSell,
+		Quantity:       qty.String(),
+		Price:          price.String(),
+		StopPrice:      stop.String(),
+		StopLimitPrice: stopLimit.String(),
+	})
 	if err != nil {
-		sess.Answerf("ERROR ON SELL ORDER FOR %v of %v: %v", free, sess.selected, err)
+		sess.Answerf("ERROR ON OCO ORDER FOR %v of %v: %v", qty.StringCompact(), sess.selected, err)
 		return
 	}
-	if order.Status == binance.OrderStatusTypeRejected {
-		sess.Answerf("ORDER REJECTED!!!!")
+	sess.Answerf("OCO list %v (%v)", list.OrderListID, list.ListOrderStatus)
+	for _, order := range list.OrderReports {
+		sess.journalOrder(order.Symbol, order.Side, order.Type, order.OrderID, order.OrigQuantity, order.Price, order.StopPrice, order.Status)
+		trigger := ""
+		if stop := FromS(order.StopPrice); stop.Valid() && stop.Sign() > 0 {
+			trigger = fmt.Sprintf(" trigger@%v", order.StopPrice)
+		}
+		sess.Answerf("  %v %v [%v of %v@%v%v (%v)]", order.Type, order.Side, order.OrigQuantity, order.Symbol, order.Price, trigger, order.Status)
 	}
-	sess.Answerf("%v [%v of %v@%v (%v executed, %v)]", order.Side, order.OrigQuantity, order.Symbol, order.Price, FromS(order.ExecutedQuantity).Div(FromS(order.OrigQuantity)).FormatPercent(), order.Status)
 }
 
-func (sess *Session) SellWall(arg string) {
+// MarketBuy buys immediately for the invest amount, converted to the quote asset
+func (sess *Session) MarketBuy() {
 	if sess.selected == "" {
 		sess.Answer("NO SYMBOL SELECTED!")
 		return
 	}
 
+	quoteQty := sess.maxInvest.Div(sess.quoteRate).FloorTo(minPrecision)
+	if !quoteQty.Valid() {
+		sess.Answerf("ORDER NOT POSSIBLE FOR %v: invest amount in %v unknown: %v", sess.selected, sess.currency, quoteQty)
+		return
+	}
+	if filters := sess.filters(); quoteQty.Cmp(filters.MinNotional) < 0 {
+		sess.Answerf("ORDER NOT POSSIBLE FOR %v of %v: order value %v is below the minimum of %v", quoteQty, sess.selected, quoteQty, filters.MinNotional)
+		return
+	}
+
+	current := sess.livePrice(sess.selected)
+	preview := sess.preview(binance.SideTypeBuy, binance.OrderTypeMarket, quoteQty.Div(current), current)
+	if !sess.approve(preview) {
+		return
+	}
+
+	order, err := sess.exchange.CreateOrder(OrderRequest{
+		Symbol:        sess.selected,
+		Side:          binance.SideTypeBuy,
+		Type:          binance.OrderTypeMarket,
+		QuoteOrderQty: quoteQty.String(),
+	})
+	if err != nil {
+		sess.Answerf("ERROR ON MARKET BUY FOR %v of %v: %v", quoteQty, sess.selected, err)
+		return
+	}
+	sess.recordOrder(preview)
+	sess.journalMarketOrder(order)
+	sess.showMarketOrder(order)
+}
+
+// MarketSell cancels all orders and sells the free balance immediately
+func (sess *Session) MarketSell() {
+	if sess.selected == "" {
+		sess.Answer("NO SYMBOL SELECTED!")
+		return
+	}
+
+	// the confirmation is asked before the orders are canceled
+	filters := sess.filters()
+	current := sess.livePrice(sess.selected)
+	free, locked := sess.balance()
+	if !sess.approveReplacing(sess.preview(binance.SideTypeSell, binance.OrderTypeMarket, filters.Quantity(free.Add(locked)), current)) {
+		return
+	}
+
 	sess.CancelAllOrders()
-	free, locked := Balance(sess.client, sess.selected)
-	if locked.V != 0 {
+	free, locked = sess.balance()
+	if locked.Sign() != 0 {
 		sess.Answerf("WARNING: locked balance of %v not in order!", locked)
 	}
 
-	maxMult := sess.sellMaxMult
-	if arg != "" {
-		maxMult = FromS(arg)
+	qty := filters.Quantity(free)
+	if err := filters.Check(qty, current); err != nil {
+		sess.Answerf("ORDER NOT POSSIBLE FOR %v of %v: %v", qty.StringCompact(), sess.selected, err)
+		return
 	}
 
-	steps := 4
-	qty := free.Div(FromI(steps)).Floor()
-	maxLimit := sess.basePrice.Mult(maxMult)
-	deltaPerStep := maxLimit.Sub(sess.basePrice).Div(FromI(steps))
-	for step := steps; step > 0; step-- {
-		limit := sess.basePrice.Add(deltaPerStep.Mult(FromI(step)))
+	order, err := sess.exchange.CreateOrder(OrderRequest{
+		Symbol:   sess.selected,
+		Side:     binance.SideTypeSell,
+		Type:     binance.OrderTypeMarket,
+		Quantity: qty.String(),
+	})
+	if err != nil {
+		sess.Answerf("ERROR ON MARKET SELL FOR %v of %v: %v", qty.StringCompact(), sess.selected, err)
+		return
+	}
+	sess.journalMarketOrder(order)
+	sess.showMarketOrder(order)
+}
+
+// showMarketOrder prints the average fill price and the fees of an executed order
+func (sess *Session) showMarketOrder(order *binance.CreateOrderResponse) {
+	qty := FromI(0)
+	quoteQty := FromI(0)
+	fees := make(map[string]F)
+	var feeAssets []string
+	for _, fill := range order.Fills {
+		fillQty := FromS(fill.Quantity)
+		qty = qty.Add(fillQty)
+		quoteQty = quoteQty.Add(fillQty.Mult(FromS(fill.Price)))
+		if _, exist := fees[fill.CommissionAsset]; !exist {
+			fees[fill.CommissionAsset] = FromI(0)
+			feeAssets = append(feeAssets, fill.CommissionAsset)
+		}
+		fees[fill.CommissionAsset] = fees[fill.CommissionAsset].Add(FromS(fill.Commission))
+	}
+
+	sess.Answerf("%v [%v of %v (%v, %v fills)]", order.Side, order.ExecutedQuantity, order.Symbol, order.Status, len(order.Fills))
+	if qty.Sign() > 0 {
+		avg := quoteQty.Div(qty)
+		sess.Answerf("  avg price: %v (%v)", avg, avg.Sub(sess.basePrice).Div(sess.basePrice).FormatPercent())
+		_, quote := sess.assets()
+		sess.Answerf("      total: %v %v / %v", quoteQty, quote, sess.formatValue(quoteQty.Mult(sess.quoteRate)))
+	}
+	for _, asset := range feeAssets {
+		sess.Answerf("        fee: %v %v", fees[asset].StringCompact(), asset)
+	}
+}
 
-		order, err := sess.client.NewCreateOrderService().Symbol(sess.selected).
-			Side(binance.SideTypeSell).Type(binance.OrderTypeLimit).
-			TimeInForce(binance.TimeInForceTypeGTC).Quantity(qty.String()).
-			Price(limit.String()).Do(context.Background())
+func (sess *Session) CancelAllOrders() {
+	if sess.dryRun {
+		sess.Answerf("DRY RUN: cancel all open orders of %v", sess.selected)
+		return
+	}
+	orders, err := sess.exchange.OpenOrders(sess.selected)
+	if len(orders) > 0 || err != nil {
+		err := sess.exchange.CancelOpenOrders(sess.selected)
 		if err != nil {
-			sess.Answerf("ERROR ON SELL ORDER FOR %v of %v: %v", free, sess.selected, err)
+			sess.Answerf("ERROR ON CANCEL ORDERS %v", err)
 			return
 		}
-		if order.Status == binance.OrderStatusTypeRejected {
-			sess.Answerf("ORDER REJECTED!!!!")
-		}
-		sess.Answerf("%v [%v of %v@%v (%v executed, %v)]", order.Side, order.OrigQuantity, order.Symbol, order.Price, FromS(order.ExecutedQuantity).Div(FromS(order.OrigQuantity)).FormatPercent(), order.Status)
+		time.Sleep(time.Millisecond * 200)
 	}
 }
 
+func (sess *Session) SellAllNow(multS string) {
+	if sess.selected == "" {
+		sess.Answer("NO SYMBOL SELECTED!")
+		return
+	}
+
+	mult := sess.sellMinMult
+	if multS != "" {
+		mult = FromS(multS)
+	}
+
+	sess.sellAll(sess.basePrice.Mult(mult))
+}
+
+// sellAll cancels all orders and places a sell limit for the free balance.
+// It returns false, if the sell was not placed.
+func (sess *Session) sellAll(limit F) bool {
+	// the confirmation is asked before the orders are canceled
+	free, locked := sess.balance()
+	filters := sess.filters()
+	if !sess.approveReplacing(sess.preview(binance.SideTypeSell, binance.OrderTypeLimit, filters.Quantity(free.Add(locked)), filters.Price(limit, binance.SideTypeSell))) {
+		return false
+	}
+
+	sess.CancelAllOrders()
+	free, locked = sess.balance()
+	if locked.Sign() != 0 {
+		sess.Answerf("WARNING: locked balance of %v not in order!", locked)
+	}
+
+	return sess.placeLimitOrder(binance.SideTypeSell, free, limit)
+}
+
 func (sess *Session) Price(symbol string) {
-	p := Price(sess.client, sess.selected)
+	p := sess.livePrice(sess.selected)
 	percent := p.Sub(sess.basePrice).Div(sess.basePrice)
 	sess.Answerf("price is %v (%v)", p, percent.FormatPercent())
 }
 
 func (sess *Session) OrderHistory(showClosed bool) {
 	now := time.Now().Unix() * 1000
-	trades, err := sess.client.NewListTradesService().Symbol(sess.selected).Limit(10).Do(context.Background())
+	trades, err := sess.exchange.Trades(sess.selected, 10)
 	if err != nil {
 		sess.Answerf("ERROR TRADES ORDERS: %v", err)
 	}
 
 	var orders []*binance.Order
 	if showClosed {
-		orders, err = sess.client.NewListOrdersService().Symbol(sess.selected).Limit(10).Do(context.Background())
+		orders, err = sess.exchange.Orders(sess.selected, 10)
 	} else {
-		orders, err = sess.client.NewListOpenOrdersService().Symbol(sess.selected).Do(context.Background())
+		orders, err = sess.exchange.OpenOrders(sess.selected)
 	}
 
 	if err != nil {
 		sess.Answerf("ERROR LIST ORDERS: %v", err)
 	}
 
-	currentPrice := Price(sess.client, sess.selected)
+	currentPrice := sess.livePrice(sess.selected)
 	for i := len(orders) - 1; i >= 0; i-- {
 		order := orders[i]
 		if showClosed || order.Status == binance.OrderStatusTypeNew || order.Status == binance.OrderStatusTypePartiallyFilled {
 			distance := ""
+			trigger := ""
+			if stop := FromS(order.StopPrice); stop.Valid() && stop.Sign() > 0 {
+				trigger = fmt.Sprintf(" %v trigger@%v", order.Type, order.StopPrice)
+			}
+			if order.OrderListId >= 0 {
+				trigger += fmt.Sprintf(" [OCO list %v]", order.OrderListId)
+			}
 			if order.Status == binance.OrderStatusTypeNew || order.Status == binance.OrderStatusTypePartiallyFilled {
-				d := FromS(order.Price).Sub(currentPrice).Div(currentPrice)
-				if order.Side == binance.SideTypeSell && d.V < 0 {
+				target := FromS(order.Price)
+				if trigger != "" {
+					target = FromS(order.StopPrice)
+				}
+				d := target.Sub(currentPrice).Div(currentPrice)
+				if order.Side == binance.SideTypeSell && d.Sign() < 0 {
 					d = d.Mult(FromF(-1))
 				}
-				if d.V > 0 {
+				if d.Sign() > 0 {
 					distance = "-->" + d.FormatPercent()
 				}
 			}
-			sess.Answerf("%v %v, @%v (%v %v) %v", order.Side, FromS(order.OrigQuantity).StringCompact(), order.Price, FromS(order.ExecutedQuantity).Div(FromS(order.OrigQuantity)).FormatPercent(), order.Status, distance)
+			sess.Answerf("%v %v, @%v%v (%v %v) %v", order.Side, FromS(order.OrigQuantity).StringCompact(), order.Price, trigger, FromS(order.ExecutedQuantity).Div(FromS(order.OrigQuantity)).FormatPercent(), order.Status, distance)
 			for _, trade := range trades {
 				if trade.OrderID == order.OrderID {
 					sess.Answerf("  -> %v: %v of @%v", time.Millisecond*time.Duration(now-trade.Time), FromS(trade.Quantity).StringCompact(), trade.Price)
@@ -299,64 +671,153 @@ func (sess *Session) Info() {
 		return
 	}
 	sess.Answerf("\n------ %v --------\n", sess.selected)
-	p := Price(sess.client, sess.selected)
+	p := sess.livePrice(sess.selected)
 	percentCurrentPrice := p.Sub(sess.basePrice).Div(sess.basePrice)
 	sess.Answerf("    price: %v (%v)", p, percentCurrentPrice.FormatPercent())
 	percentBasePrice := sess.basePrice.Sub(sess.avg24h).Div(sess.avg24h)
 	sess.Answerf("basePrice: %v (%v)", sess.basePrice, percentBasePrice.FormatPercent())
 	sess.Answerf("  24h AVG: %v\n", sess.avg24h)
 
-	free, locked := Balance(sess.client, sess.selected)
+	free, locked := sess.balance()
 	sess.Answerf("    total: %v", free.Add(locked).StringCompact())
 	sess.Answerf("     free: %v", free.StringCompact())
-	sess.Answerf("   locked: %v\n", locked.StringCompact())
+	sess.Answerf("   locked: %v", locked.StringCompact())
+	if p, err := sess.selectedPnL(); err != nil {
+		sess.Answerf("      pnl: %v\n", err)
+	} else {
+		realized := sess.formatValue(p.realized.Mult(sess.quoteRate))
+		unrealized := sess.formatValue(p.unrealized(sess.livePrice(sess.selected)).Mult(sess.quoteRate))
+		sess.Answerf("      pnl: %v realized, %v unrealized\n", realized, unrealized)
+	}
 
 	sess.OrderHistory(false)
 }
 
 func (sess *Session) dispatch() {
-	for line := range sess.in {
-		pairs := strings.SplitN(line, " ", 2)
-		arg := ""
-		if len(pairs) > 1 {
-			arg = pairs[1]
+	if err := checkCurrency(sess.currency); err != nil {
+		sess.Answerf("INVALID CURRENCY, USING EUR: %v", err)
+		sess.currency = "EUR"
+	}
+	sess.subscribeUserData()
+	sess.restoreState()
+	for {
+		select {
+		case line := <-sess.in:
+			sess.confirmed = false
+			sess.exiting = false
+			sess.command = line
+			sess.execute(line)
+		case task := <-sess.tasks:
+			sess.confirmed = false
+			sess.exiting = false
+			sess.command = ""
+			task()
 		}
-		switch cmd := pairs[0]; cmd {
-		case "config":
-			sess.Answerf("\n-------- config ----------")
-			sess.ShowConfig()
-		case "cancel", "c":
-			sess.Answerf("\n-------- cancel ----------")
-			sess.CancelAllOrders()
-			sess.Info()
-		case "price", "p":
-			sess.Answerf("\n-------- price -----------")
-			sess.Price(arg)
-		case "buy", "b":
-			sess.Answerf("\n-------- buy  ------------")
-			sess.Buy(arg)
-		case "sell", "s":
-			sess.Answerf("\n-------- sell ------------")
-			sess.SellAllNow(arg)
-		case "sell-wall", "sw":
-			sess.Answerf("\n-------- sell wall----------")
-			sess.SellWall(arg)
-		case "", "init", "i":
-			sess.Init(arg)
-		case "history", "h":
-			sess.Answerf("\n-------- history ---------")
-			sess.OrderHistory(true)
-		case "symbol-info":
-			sess.Answerf("\n-------- symbol info ---------")
-			sess.SymbolInfo()
-		case "set invest":
-
-		default:
-			if sess.selected == "" {
-				sess.Init(cmd)
-			} else {
-				sess.Answer(fmt.Sprintf("not a command: %v", line))
-			}
+	}
+}
+
+func (sess *Session) execute(line string) {
+	if sess.watching != nil {
+		// any input stops the watch mode
+		sess.stopWatch()
+		return
+	}
+
+	pairs := strings.SplitN(line, " ", 2)
+	arg := ""
+	if len(pairs) > 1 {
+		arg = pairs[1]
+	}
+	switch cmd := pairs[0]; cmd {
+	case "config":
+		sess.Answerf("\n-------- config ----------")
+		sess.ShowConfig()
+	case "cancel", "c":
+		sess.Answerf("\n-------- cancel ----------")
+		sess.CancelAllOrders()
+		sess.Info()
+	case "price", "p":
+		sess.Answerf("\n-------- price -----------")
+		sess.Price(arg)
+	case "buy", "b":
+		sess.Answerf("\n-------- buy  ------------")
+		sess.Buy(arg)
+	case "sell", "s":
+		sess.Answerf("\n-------- sell ------------")
+		sess.SellAllNow(arg)
+	case "buy-ladder", "bl":
+		sess.Answerf("\n-------- buy ladder ------")
+		sess.BuyLadder(arg)
+	case "market-buy", "mb":
+		sess.Answerf("\n-------- market buy ------")
+		sess.MarketBuy()
+	case "market-sell", "ms":
+		sess.Answerf("\n-------- market sell -----")
+		sess.MarketSell()
+	case "sell-wall", "sw":
+		sess.Answerf("\n-------- sell wall----------")
+		sess.SellWall(arg)
+	case "sell-wall-preview", "swp":
+		sess.Answerf("\n-------- sell wall preview -")
+		sess.SellWallPreview(arg)
+	case "stop":
+		sess.Answerf("\n-------- stop loss -------")
+		sess.StopOrder(binance.OrderTypeStopLossLimit, arg)
+	case "take-profit", "tp":
+		sess.Answerf("\n-------- take profit -----")
+		sess.StopOrder(binance.OrderTypeTakeProfitLimit, arg)
+	case "trail":
+		sess.Answerf("\n-------- trail -----------")
+		sess.Trail(arg)
+	case "oco":
+		sess.Answerf("\n-------- oco -------------")
+		sess.Oco(arg)
+	case "", "init", "i":
+		sess.Init(arg)
+	case "watch", "w":
+		sess.Answerf("\n-------- watch -----------")
+		sess.Watch()
+	case "dry-run":
+		sess.Answerf("\n-------- dry run ---------")
+		sess.DryRun(arg)
+	case "journal", "j":
+		sess.Answerf("\n-------- journal ---------")
+		sess.Journal(arg)
+	case "pnl":
+		sess.Answerf("\n-------- pnl -------------")
+		sess.PnL()
+	case "portfolio", "pf":
+		sess.Answerf("\n-------- portfolio -------")
+		sess.Portfolio(arg)
+	case "backtest", "bt":
+		sess.Answerf("\n-------- backtest --------")
+		sess.Backtest(arg)
+	case "risk":
+		sess.Answerf("\n-------- risk limits -----")
+		sess.ShowRisk()
+	case "panic":
+		sess.Answerf("\n-------- PANIC -----------")
+		sess.Panic(arg)
+	case "history", "h":
+		sess.Answerf("\n-------- history ---------")
+		sess.OrderHistory(true)
+	case "symbol-info":
+		sess.Answerf("\n-------- symbol info ---------")
+		sess.SymbolInfo()
+	case "set":
+		sess.Answerf("\n-------- set -------------")
+		sess.Set(arg)
+	case "get":
+		sess.Answerf("\n-------- settings --------")
+		sess.ShowSettings()
+	case "reset":
+		sess.Answerf("\n-------- reset -----------")
+		sess.Reset()
+	default:
+		if sess.selected == "" {
+			sess.Init(cmd)
+		} else {
+			sess.Answer(fmt.Sprintf("not a command: %v", line))
 		}
 	}
 }
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/adshao/go-binance/v2"
+	"github.com/smancke/trading-shell/binancefake"
+	"github.com/smancke/trading-shell/config"
+)
+
+// doneMarker is answered by the session after each command of a test shell
+const doneMarker = "--done--"
+
+// testShell drives a session against the fake server and collects its output
+type testShell struct {
+	t     *testing.T
+	srv   *binancefake.Server
+	sess  *Session
+	mutex sync.Mutex
+	out   []string
+	done  chan bool
+}
+
+// newTestShell starts a session with XYZBTC at 0.00001 BTC, BTC at 50000€ and a balance of 0.01 BTC.
+// The setup may change the fake server and the config before.
+func newTestShell(t *testing.T, setup func(srv *binancefake.Server, c *config.Config)) *testShell {
+	srv := binancefake.NewServer()
+	t.Cleanup(srv.Close)
+	binance.BaseCombinedMainURL = srv.CombinedStreamURL()
+	binance.BaseWsMainURL = srv.StreamURL()
+	srv.AddSymbol("XYZBTC", "XYZ", "BTC",
+		map[string]interface{}{"filterType": "LOT_SIZE", "minQty": "0.1", "maxQty": "100000", "stepSize": "0.1"},
+		map[string]interface{}{"filterType": "PRICE_FILTER", "minPrice": "0.00000010", "maxPrice": "1000", "tickSize": "0.00000010"},
+		map[string]interface{}{"filterType": "MIN_NOTIONAL", "minNotional": "0.0001"})
+	srv.AddSymbol("BTCEUR", "BTC", "EUR")
+	srv.SetPrice("XYZBTC", "0.00001000")
+	srv.SetPrice("BTCEUR", "50000")
+	srv.SetBalance("BTC", "0.01", "0")
+
+	c := config.DefaultConfig()
+	c.StateFile = ""
+	c.Journal = ""
+	c.BacktestCache = ""
+	if setup != nil {
+		setup(srv, c)
+	}
+	shell := &testShell{t: t, srv: srv, done: make(chan bool)}
+	shell.sess = StartSession(NewBinanceExchange(srv.BinanceClient()), c)
+	started := make(chan bool)
+	var once sync.Once
+	go func() {
+		for line := range shell.sess.out {
+			if line == doneMarker {
+				shell.done <- true
+				continue
+			}
+			shell.mutex.Lock()
+			shell.out = append(shell.out, line)
+			shell.mutex.Unlock()
+			if strings.Contains(line, "-------- config") {
+				once.Do(func() { close(started) })
+			}
+		}
+	}()
+	<-started
+	shell.wait()
+	return shell
+}
+
+// wait blocks until the running command is finished and its output is collected,
+// by queueing a task behind it, which answers the marker
+func (shell *testShell) wait() {
+	shell.sess.tasks <- func() { shell.sess.Answer(doneMarker) }
+	<-shell.done
+}
+
+// run executes the command and returns its output
+func (shell *testShell) run(cmd string) string {
+	return shell.collect(cmd, func() {
+		shell.sess.Put(cmd)
+		for len(shell.sess.in) > 0 {
+			runtime.Gosched()
+		}
+	})
+}
+
+// answer executes the command, answers its confirmation and returns the output
+func (shell *testShell) answer(cmd, answer string) string {
+	return shell.collect(cmd, func() {
+		shell.sess.Put(cmd)
+		shell.sess.Put(answer)
+		for len(shell.sess.in) > 0 {
+			runtime.Gosched()
+		}
+	})
+}
+
+// task executes the function by the dispatcher and returns its output
+func (shell *testShell) task(name string, task func()) string {
+	return shell.collect(name, func() {
+		shell.sess.tasks <- task
+	})
+}
+
+// collect returns the output of the started command, once it is finished
+func (shell *testShell) collect(name string, start func()) string {
+	shell.mutex.Lock()
+	shell.out = nil
+	shell.mutex.Unlock()
+
+	start()
+	shell.wait()
+
+	shell.mutex.Lock()
+	defer shell.mutex.Unlock()
+	output := strings.Join(shell.out, "\n")
+	shell.t.Logf("> %v\n%v", name, output)
+	return output
+}
+
+// openOrders returns the open orders of the fake server
+func (shell *testShell) openOrders() []binance.Order {
+	var open []binance.Order
+	for _, o := range shell.srv.Orders() {
+		if o.Status == binance.OrderStatusTypeNew {
+			open = append(open, o)
+		}
+	}
+	return open
+}
+
+func TestSessionBuyAndSellWall(t *testing.T) {
+	shell := newTestShell(t, nil)
+
+	if out := shell.run("xyzbtc"); !strings.Contains(out, "basePrice: 0.00001000") {
+		t.Errorf("expected the basePrice of the selected symbol, got:\n%v", out)
+	}
+
+	// 50€ at 50000€ are 0.001 BTC or 100 XYZ at the basePrice, which is the current price
+	shell.run("buy 1")
+	orders := shell.srv.Orders()
+	if len(orders) != 1 || orders[0].Side != binance.SideTypeBuy || FromS(orders[0].OrigQuantity).Cmp(FromI(100)) != 0 ||
+		orders[0].Price != "0.00001000" || orders[0].Status != binance.OrderStatusTypeFilled {
+		t.Fatalf("expected a filled buy of 100 XYZ at 0.00001000, got %+v", orders)
+	}
+
+	shell.run("sell-wall 2")
+	open := shell.openOrders()
+	if len(open) != 4 {
+		t.Fatalf("expected 4 sell limits, got %+v", open)
+	}
+	total := FromI(0)
+	for _, o := range open {
+		if o.Side != binance.SideTypeSell || FromS(o.Price).Cmp(FromS("0.00001")) <= 0 || FromS(o.Price).Cmp(FromS("0.00002")) > 0 {
+			t.Errorf("unexpected sell wall order: %+v", o)
+		}
+		total = total.Add(FromS(o.OrigQuantity))
+	}
+	if total.Cmp(FromI(100)) != 0 {
+		t.Errorf("expected the sell wall to sell 100 XYZ, got %v", total)
+	}
+
+	// a second wall replaces the first one
+	shell.run("sell-wall 3")
+	if open = shell.openOrders(); len(open) != 4 || open[0].Price != "0.00003000" {
+		t.Errorf("expected 4 sell limits up to 0.00003000, got %+v", open)
+	}
+
+	out := shell.run("history")
+	if strings.Count(out, "SELL") < 8 || !strings.Contains(out, "BUY") {
+		t.Errorf("expected the buy and both sell walls in the history, got:\n%v", out)
+	}
+}
+
+func TestSessionWithoutSymbol(t *testing.T) {
+	shell := newTestShell(t, nil)
+
+	for _, cmd := range []string{"buy", "sell-wall", "pnl"} {
+		if out := shell.run(cmd); !strings.Contains(out, "NO SYMBOL SELECTED!") {
+			t.Errorf("%v: expected an error without selected symbol, got:\n%v", cmd, out)
+		}
+	}
+	if len(shell.srv.Orders()) != 0 {
+		t.Errorf("expected no orders, got %+v", shell.srv.Orders())
+	}
+}
+
+func TestMarketBuyWithUnknownRate(t *testing.T) {
+	// there is no market to convert BTC to USDT
+	shell := newTestShell(t, func(srv *binancefake.Server, c *config.Config) {
+		c.Currency = "USDT"
+		c.ConfirmAbove = 0
+	})
+	shell.run("xyzbtc")
+	if out := shell.run("market-buy"); !strings.Contains(out, "ORDER NOT POSSIBLE FOR XYZBTC: invest amount in USDT unknown") {
+		t.Errorf("expected the market buy to be refused, got:\n%v", out)
+	}
+	if len(shell.srv.Orders()) != 0 {
+		t.Errorf("expected no orders, got %+v", shell.srv.Orders())
+	}
+}
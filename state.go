@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+)
+
+// defaultMaxInvest is the invest amount per buy in the reporting currency
+const defaultMaxInvest = 50.0
+
+// sessionState is the part of the session, which is kept across restarts
+type sessionState struct {
+	Selected         string `json:"selected"`
+	BasePrice        string `json:"basePrice,omitempty"`
+	Currency         string `json:"currency,omitempty"` // the reporting currency of the values
+	MaxInvest        string `json:"maxInvest"`
+	BuyMaxMult       string `json:"buyMaxMult"`
+	SellMaxMult      string `json:"sellMaxMult"`
+	SellMinMult      string `json:"sellMinMult"`
+	WallSteps        int    `json:"wallSteps,omitempty"`
+	WallSpacing      string `json:"wallSpacing,omitempty"`
+	WallDistribution string `json:"wallDistribution,omitempty"`
+	Quote            string `json:"quote,omitempty"`
+	DailyDate        string `json:"dailyDate,omitempty"`
+	Daily            string `json:"daily,omitempty"`
+}
+
+func readState(file string) (*sessionState, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	state := &sessionState{}
+	return state, json.Unmarshal(b, state)
+}
+
+func (state *sessionState) write(file string) error {
+	b, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, b, 0600)
+}
+
+// setDefaults sets the session parameters to their initial values
+func (sess *Session) setDefaults() {
+	sess.maxInvest = FromF(defaultMaxInvest)
+	sess.buyMaxMult = FromF(1.2)
+	sess.sellMaxMult = FromF(4.5)
+	sess.sellMinMult = FromF(1)
+	sess.wallShape = defaultWallShape()
+	sess.quote = quoteAssets[0]
+}
+
+// saveState writes the selected symbol and the session parameters to the state file
+func (sess *Session) saveState() {
+	if sess.stateFile == "" {
+		return
+	}
+	state := &sessionState{
+		Selected:         sess.selected,
+		Currency:         sess.currency,
+		MaxInvest:        sess.maxInvest.StringCompact(),
+		BuyMaxMult:       sess.buyMaxMult.StringCompact(),
+		SellMaxMult:      sess.sellMaxMult.StringCompact(),
+		SellMinMult:      sess.sellMinMult.StringCompact(),
+		WallSteps:        sess.wallShape.steps,
+		WallSpacing:      sess.wallShape.spacing,
+		WallDistribution: sess.wallShape.distribution,
+		Quote:            sess.quote,
+		DailyDate:        sess.daily.day,
+	}
+	if sess.daily.day != "" {
+		state.Daily = sess.daily.value.StringCompact()
+	}
+	if sess.selected != "" && sess.basePrice.Valid() {
+		state.BasePrice = sess.basePrice.StringCompact()
+	}
+	if err := state.write(sess.stateFile); err != nil {
+		sess.Answerf("ERROR ON SAVING STATE: %v", err)
+	}
+}
+
+// restoreState loads the session parameters and the selected symbol from the state file
+func (sess *Session) restoreState() {
+	if sess.stateFile == "" {
+		return
+	}
+	state, err := readState(sess.stateFile)
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil {
+		sess.Answerf("ERROR ON READING STATE %v: %v", sess.stateFile, err)
+		return
+	}
+
+	sellMinMult, sellMaxMult := sess.sellMinMult, sess.sellMaxMult
+	for _, p := range []struct {
+		value string
+		f     *F
+	}{
+		{state.MaxInvest, &sess.maxInvest},
+		{state.BuyMaxMult, &sess.buyMaxMult},
+		{state.SellMaxMult, &sess.sellMaxMult},
+		{state.SellMinMult, &sess.sellMinMult},
+	} {
+		if v := FromS(p.value); v.Valid() && v.Sign() > 0 {
+			*p.f = v
+		}
+	}
+	// like on set, the sell min has to be below the sell max
+	if sess.sellMinMult.Cmp(sess.sellMaxMult) >= 0 {
+		sess.Answerf("WARNING: SELL MIN %v OF THE STATE IS NOT BELOW SELL MAX %v, KEEPING %v AND %v",
+			sess.sellMinMult.StringCompact(), sess.sellMaxMult.StringCompact(), sellMinMult.StringCompact(), sellMaxMult.StringCompact())
+		sess.sellMinMult, sess.sellMaxMult = sellMinMult, sellMaxMult
+	}
+
+	if state.WallSteps >= 1 && state.WallSteps <= maxWallSteps {
+		sess.wallShape.steps = state.WallSteps
+	}
+	sess.wallShape.setSpacing(state.WallSpacing)
+	sess.wallShape.setDistribution(state.WallDistribution)
+	sess.setQuote(state.Quote)
+
+	if value := FromS(state.Daily); state.DailyDate != "" && value.Valid() {
+		sess.daily = dailyVolume{day: state.DailyDate, value: value}
+	}
+	sess.convertState(state.Currency)
+
+	if state.Selected != "" && sess.selectSymbol(state.Selected) {
+		if basePrice := FromS(state.BasePrice); basePrice.Valid() && basePrice.Sign() > 0 {
+			sess.basePrice = basePrice
+		}
+		sess.Answerf("restored %v with basePrice %v", sess.selected, sess.basePrice)
+	}
+}
+
+// convertState converts the invest amount and the daily volume, if they were saved in another
+// reporting currency. Without a conversion, both are reset.
+func (sess *Session) convertState(currency string) {
+	if currency == "" || currency == sess.currency {
+		return
+	}
+	rate := sess.currencyPrice(currencies[currency])
+	if !rate.Valid() || rate.Sign() <= 0 {
+		sess.maxInvest = FromF(defaultMaxInvest)
+		sess.daily = dailyVolume{}
+		sess.Answerf("WARNING: THE STATE WAS SAVED IN %v, RESET THE INVEST AMOUNT TO %v AND THE DAILY VOLUME: %v", currency, sess.formatValue(sess.maxInvest), rate)
+		return
+	}
+	sess.maxInvest = sess.maxInvest.Mult(rate)
+	sess.daily.value = sess.daily.value.Mult(rate)
+	sess.Answerf("WARNING: THE STATE WAS SAVED IN %v, CONVERTED THE INVEST AMOUNT TO %v", currency, sess.formatValue(sess.maxInvest))
+}
+
+// Reset sets all session parameters back to the defaults and unselects the symbol
+func (sess *Session) Reset() {
+	sess.setDefaults()
+	if sess.trailing != nil {
+		sess.stopTrailing()
+	}
+	sess.selected = ""
+	sess.live.next()
+	sess.basePrice = F{}
+	if sess.stateFile != "" {
+		if err := os.Remove(sess.stateFile); err != nil && !os.IsNotExist(err) {
+			sess.Answerf("ERROR ON REMOVING STATE: %v", err)
+		}
+	}
+	sess.ShowSettings()
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/smancke/trading-shell/binancefake"
+	"github.com/smancke/trading-shell/config"
+)
+
+func TestStateCurrency(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	today := time.Now().Format("2006-01-02")
+	state := &sessionState{Currency: "EUR", MaxInvest: "100", BuyMaxMult: "1.2", SellMaxMult: "4.5", SellMinMult: "1", DailyDate: today, Daily: "200"}
+	if err := state.write(stateFile); err != nil {
+		t.Fatal(err)
+	}
+
+	// the values are converted to the new reporting currency
+	shell := newTestShell(t, func(srv *binancefake.Server, c *config.Config) {
+		srv.AddSymbol("EURUSDT", "EUR", "USDT")
+		srv.SetPrice("EURUSDT", "1.2")
+		c.StateFile = stateFile
+		c.Currency = "USDT"
+	})
+	if out := shell.run("config"); !strings.Contains(out, "Invest: 120 USDT") {
+		t.Errorf("expected the invest amount in USDT, got:\n%v", out)
+	}
+	if out := shell.run("risk"); !strings.Contains(out, "buys placed today 240.00 USDT") {
+		t.Errorf("expected the daily volume in USDT, got:\n%v", out)
+	}
+	shell.run("xyzbtc")
+	if saved, err := readState(stateFile); err != nil || saved.Currency != "USDT" || saved.MaxInvest != "120" {
+		t.Errorf("expected the state to be saved in USDT, got %+v (%v)", saved, err)
+	}
+
+	// without a conversion, the invest amount and the daily volume are reset
+	shell = newTestShell(t, func(srv *binancefake.Server, c *config.Config) {
+		c.StateFile = stateFile
+		c.Currency = "GBP"
+	})
+	if out := shell.run("config"); !strings.Contains(out, "Invest: 50 GBP") {
+		t.Errorf("expected the default invest amount, got:\n%v", out)
+	}
+	if out := shell.run("risk"); !strings.Contains(out, "buys placed today 0.00£") {
+		t.Errorf("expected the daily volume to be reset, got:\n%v", out)
+	}
+}
+
+func TestStateSellMultipliers(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	state := &sessionState{MaxInvest: "100", BuyMaxMult: "1.2", SellMaxMult: "2", SellMinMult: "3"}
+	if err := state.write(stateFile); err != nil {
+		t.Fatal(err)
+	}
+
+	// the sell min of the state is not below the sell max, like it is checked by set
+	shell := newTestShell(t, func(srv *binancefake.Server, c *config.Config) {
+		c.StateFile = stateFile
+	})
+	out := shell.run("config")
+	if !strings.Contains(out, "Invest: 100 EUR") || !strings.Contains(out, "Sell Max: 450.00%") || !strings.Contains(out, "Sell Min: 100.00%") {
+		t.Errorf("expected the invest amount of the state and the default sell multipliers, got:\n%v", out)
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adshao/go-binance/v2"
+)
+
+// watchInterval is the time between two outputs of the watch mode
+const watchInterval = time.Second
+
+// livePrices caches the last trade prices of the subscribed symbols,
+// received over the websocket stream of the exchange.
+type livePrices struct {
+	mutex  sync.RWMutex
+	prices map[string]F
+	stream int           // id of the current stream, prices of other streams are ignored
+	stopC  chan struct{} // stops the current stream, nil if there is none
+}
+
+func newLivePrices() *livePrices {
+	return &livePrices{prices: make(map[string]F)}
+}
+
+// get returns the cached price of the symbol, or false if there is none
+func (live *livePrices) get(symbol string) (F, bool) {
+	live.mutex.RLock()
+	defer live.mutex.RUnlock()
+	price, exist := live.prices[symbol]
+	return price, exist
+}
+
+// next stops the current stream, drops all prices and returns the id for the next stream
+func (live *livePrices) next() int {
+	live.mutex.Lock()
+	defer live.mutex.Unlock()
+	if live.stopC != nil {
+		close(live.stopC)
+	}
+	live.stopC = nil
+	live.prices = make(map[string]F)
+	live.stream++
+	return live.stream
+}
+
+// started sets the stop channel of the stream
+func (live *livePrices) started(stream int, stopC chan struct{}) {
+	live.mutex.Lock()
+	defer live.mutex.Unlock()
+	if live.stream == stream {
+		live.stopC = stopC
+	} else {
+		close(stopC)
+	}
+}
+
+// update sets the price, if it was received by the current stream
+func (live *livePrices) update(stream int, symbol string, price F) {
+	live.mutex.Lock()
+	defer live.mutex.Unlock()
+	if live.stream == stream {
+		live.prices[symbol] = price
+	}
+}
+
+// closed drops all prices, if the stream was the current one.
+// So the callers fall back to the REST api.
+func (live *livePrices) closed(stream int) {
+	live.mutex.Lock()
+	defer live.mutex.Unlock()
+	if live.stream == stream {
+		live.stopC = nil
+		live.prices = make(map[string]F)
+	}
+}
+
+// subscribePrices (re)starts the price stream for the selected symbol and BTC in the reporting currency
+func (sess *Session) subscribePrices() {
+	if !sess.streaming {
+		return
+	}
+	stream := sess.live.next()
+
+	var symbols []string
+	if btc := "BTC" + currencies[sess.currency]; sess.allSymbols[btc] != nil && btc != sess.selected {
+		symbols = append(symbols, btc)
+	}
+	if sess.selected != "" {
+		symbols = append(symbols, sess.selected)
+	}
+	if len(symbols) == 0 {
+		return
+	}
+
+	doneC, stopC, err := sess.exchange.TradeStream(symbols,
+		func(event *binance.WsCombinedTradeEvent) {
+			sess.live.update(stream, event.Data.Symbol, FromS(event.Data.Price))
+		},
+		func(err error) {
+			sess.Answerf("ERROR ON PRICE STREAM %v: %v", strings.Join(symbols, ","), err)
+		})
+	if err != nil {
+		sess.Answerf("PRICE STREAM NOT AVAILABLE: %v", err)
+		return
+	}
+	sess.live.started(stream, stopC)
+
+	go func() {
+		<-doneC
+		sess.live.closed(stream)
+	}()
+}
+
+// livePrice returns the streamed price of the symbol and falls back to the REST api,
+// if the symbol is not streamed or there was no trade since the subscription.
+// It is safe to be used from background routines.
+func (sess *Session) livePrice(symbol string) F {
+	if price, exist := sess.live.get(symbol); exist {
+		return price
+	}
+	return Price(sess.exchange, symbol)
+}
+
+// watcher prints the price of a symbol until it is stopped
+type watcher struct {
+	symbol    string
+	basePrice F
+	stop      chan struct{}
+}
+
+// Watch prints the price of the selected symbol and the change from the basePrice
+// on every change, until the next input line.
+func (sess *Session) Watch() {
+	if sess.selected == "" {
+		sess.Answer("NO SYMBOL SELECTED!")
+		return
+	}
+
+	sess.watching = &watcher{
+		symbol:    sess.selected,
+		basePrice: sess.basePrice,
+		stop:      make(chan struct{}),
+	}
+	sess.Answerf("watching %v, press enter to stop", sess.selected)
+	go sess.runWatch(sess.watching)
+}
+
+func (sess *Session) stopWatch() {
+	close(sess.watching.stop)
+	sess.watching = nil
+	sess.Answer("watch stopped")
+}
+
+func (sess *Session) runWatch(w *watcher) {
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	var last F
+	for {
+		price := sess.livePrice(w.symbol)
+		if !price.Valid() {
+			sess.Answerf("ERROR ON PRICE UPDATE: %v", price)
+		} else if last.Sign() == 0 || price.Cmp(last) != 0 {
+			change := ""
+			if last.Sign() != 0 {
+				change = price.Sub(last).Div(last).FormatPercent()
+			}
+			sess.Answerf("%v %v: %v (%v) %v", time.Now().Format("15:04:05"), w.symbol, price, price.Sub(w.basePrice).Div(w.basePrice).FormatPercent(), change)
+			last = price
+		}
+
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
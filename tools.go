@@ -1,55 +1,59 @@
 package main
 
 import (
-	"context"
 	"errors"
 	"fmt"
-	"github.com/adshao/go-binance/v2"
 	"math"
-	"strconv"
 	"strings"
+
+	"github.com/adshao/go-binance/v2"
+	"github.com/shopspring/decimal"
 )
 
-func PrintPushCoins(client *binance.Client) {
-	symbols, err := client.NewListPriceChangeStatsService().Do(context.Background())
+// PrintPushCoins lists the BTC pairs with a moderate volume and a low price in the reporting currency as csv
+func PrintPushCoins(exchange Exchange, currency string) {
+	symbols, err := exchange.PriceChangeStats()
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
+	if err := checkCurrency(currency); err != nil {
+		fmt.Println(err)
+		return
+	}
 
-	btcPrice := BTCEURPrice(client)
-	fmt.Printf("Symbol,PriceChangePercent,AvgPrice,AvgPriceEUR,Volume,VolumeEUR\n")
+	c := statsConverter(symbols, func(s *binance.PriceChangeStats) string { return s.LastPrice })
+	btcPrice := c.convert("BTC", currencies[currency])
+
+	fmt.Printf("Symbol,PriceChangePercent,AvgPrice,AvgPrice%v,Volume,Volume%v\n", currency, currency)
 	for _, s := range symbols {
 		volume := FromS(s.Volume)
-		priceAVGEUR := FromS(s.WeightedAvgPrice).Mult(btcPrice)
-		volumeEUR := FromS(s.WeightedAvgPrice).Mult(volume).Mult(btcPrice)
+		priceAVG := FromS(s.WeightedAvgPrice).Mult(btcPrice)
+		volumeValue := FromS(s.WeightedAvgPrice).Mult(volume).Mult(btcPrice)
 		if strings.HasSuffix(s.Symbol, "BTC") &&
-			volumeEUR.V > 50000 && volumeEUR.V < 4000000 &&
-			priceAVGEUR.V < 1 {
-			fmt.Printf("%v,%v,%v,%v,%v,%v\n", s.Symbol, s.PriceChangePercent, s.WeightedAvgPrice, priceAVGEUR, volume, volumeEUR)
+			volumeValue.Cmp(FromI(50000)) > 0 && volumeValue.Cmp(FromI(4000000)) < 0 &&
+			priceAVG.Cmp(FromI(1)) < 0 {
+			fmt.Printf("%v,%v,%v,%v,%v,%v\n", s.Symbol, s.PriceChangePercent, s.WeightedAvgPrice, priceAVG, volume, volumeValue)
 		}
 	}
 }
 
-func BTCEURPrice(client *binance.Client) F {
-	return Price(client, "BTCEUR")
-}
-
-func Balance(client *binance.Client, symbol string) (free F, locked F) {
-	account, err := client.NewGetAccountService().Do(context.Background())
+// Balance returns the free and locked balance of the asset
+func Balance(exchange Exchange, asset string) (free F, locked F) {
+	account, err := exchange.Account()
 	if err != nil {
 		return FromError(fmt.Errorf("could not fetch account info")), FromError(fmt.Errorf("could not fetch account info"))
 	}
 	for _, b := range account.Balances {
-		if b.Asset == symbol || b.Asset+"BTC" == symbol {
+		if b.Asset == asset {
 			return FromS(b.Free), FromS(b.Locked)
 		}
 	}
 	return F{}, F{}
 }
 
-func AvgPrice(client *binance.Client, s string) F {
-	price, err := client.NewAveragePriceService().Symbol(s).Do(context.Background())
+func AvgPrice(exchange Exchange, s string) F {
+	price, err := exchange.AvgPrice(s)
 	if err != nil {
 		return FromError(fmt.Errorf("could not fetch average %v price: %w", s, err))
 	}
@@ -57,8 +61,8 @@ func AvgPrice(client *binance.Client, s string) F {
 	return FromS(price.Price)
 }
 
-func Price(client *binance.Client, s string) F {
-	prices, err := client.NewListPricesService().Symbol(s).Do(context.Background())
+func Price(exchange Exchange, s string) F {
+	prices, err := exchange.Prices(s)
 	if err != nil {
 		return FromError(fmt.Errorf("could not fetch %v price: %w", s, err))
 	}
@@ -69,13 +73,10 @@ func Price(client *binance.Client, s string) F {
 	return FromError(fmt.Errorf("got no value for %v price", s))
 }
 
-func sToF(s string) float64 {
-	f, _ := strconv.ParseFloat(s, 64)
-	return f
-}
-
+// F is an exact decimal value, which carries an error through all calculations.
+// Once an operation fails, the result stays invalid and reports the first error.
 type F struct {
-	V   float64
+	V   decimal.Decimal
 	Err error
 }
 
@@ -87,7 +88,7 @@ func (f F) Sub(f2 F) F {
 		return f2
 	}
 	return F{
-		V: f.V - f2.V,
+		V: f.V.Sub(f2.V),
 	}
 }
 
@@ -99,7 +100,7 @@ func (f F) Add(f2 F) F {
 		return f2
 	}
 	return F{
-		V: f.V + f2.V,
+		V: f.V.Add(f2.V),
 	}
 }
 
@@ -111,7 +112,7 @@ func (f F) Mult(f2 F) F {
 		return f2
 	}
 	return F{
-		V: f.V * f2.V,
+		V: f.V.Mul(f2.V),
 	}
 }
 
@@ -122,67 +123,117 @@ func (f F) Div(f2 F) F {
 	if !f2.Valid() {
 		return f2
 	}
-	if f2.V == 0 {
+	if f2.V.IsZero() {
 		return F{
 			Err: errors.New("division by zero"),
 		}
 	}
 	return F{
-		V: f.V / f2.V,
+		V: f.V.Div(f2.V),
 	}
 }
 
+// Pow raises f to the power of exp.
+// The calculation is done in float64, so the result is not exact.
+func (f F) Pow(exp float64) F {
+	if !f.Valid() {
+		return f
+	}
+	v, _ := f.V.Float64()
+	result := math.Pow(v, exp)
+	if math.IsNaN(result) || math.IsInf(result, 0) {
+		return FromError(fmt.Errorf("%v to the power of %v is not a number", f.StringCompact(), exp))
+	}
+	return FromF(result)
+}
+
 func (f F) Floor() F {
 	if !f.Valid() {
 		return f
 	}
 	return F{
-		V: math.Floor(f.V),
+		V: f.V.Floor(),
+	}
+}
+
+// FloorTo rounds down to a multiple of step
+func (f F) FloorTo(step F) F {
+	if !f.Valid() {
+		return f
+	}
+	if !step.Valid() {
+		return step
+	}
+	if step.V.IsZero() {
+		return f
+	}
+	return F{
+		V: f.V.Div(step.V).Floor().Mul(step.V),
 	}
 }
 
+// CeilTo rounds up to a multiple of step
+func (f F) CeilTo(step F) F {
+	if !f.Valid() {
+		return f
+	}
+	if !step.Valid() {
+		return step
+	}
+	if step.V.IsZero() {
+		return f
+	}
+	return F{
+		V: f.V.Div(step.V).Ceil().Mul(step.V),
+	}
+}
+
+// Cmp compares the values and returns -1, 0 or 1 as f is less, equal or greater than f2.
+// An invalid value compares as zero, so guards have to check Valid() before.
+func (f F) Cmp(f2 F) int {
+	return f.V.Cmp(f2.V)
+}
+
+// Sign returns -1, 0 or 1 as f is negative, zero or positive. An invalid value has the sign 0.
+func (f F) Sign() int {
+	return f.V.Sign()
+}
+
 func (f F) Valid() bool {
 	return f.Err == nil
 }
 
 func (f F) String() string {
 	if f.Valid() {
-		return fmt.Sprintf("%0.8f", f.V)
+		return f.V.StringFixed(8)
 	}
 	return fmt.Sprintf("%v", f.Err)
 }
 
 func (f F) StringCompact() string {
 	if f.Valid() {
-		return strconv.FormatFloat(f.V, 'f', -1, 64)
+		return f.V.String()
 	}
 	return fmt.Sprintf("%v", f.Err)
 }
 
 func (f F) StringPrice() string {
 	if f.Valid() {
-		return strconv.FormatFloat(f.V, 'f', 8, 64)
+		return f.V.StringFixed(8)
 	}
 	return fmt.Sprintf("%v", f.Err)
 }
 
 func (f F) StringInt() string {
 	if f.Valid() {
-		return fmt.Sprintf("%0.0f", f.V)
+		return f.V.StringFixed(0)
 	}
 	return fmt.Sprintf("%v", f.Err)
 }
 
 func (f F) FormatPercent() string {
 	if f.Valid() {
-		return fmt.Sprintf("%0.2f%%", f.V*100)
-	}
-	return fmt.Sprintf("%v", f.Err)
-}
-
-func (f F) FormatEUR() string {
-	if f.Valid() {
-		return fmt.Sprintf("%0.2f€", f.V)
+		return f.V.Shift(2).StringFixed(2) + "%"
 	}
 	return fmt.Sprintf("%v", f.Err)
 }
@@ -193,11 +244,12 @@ func FromError(err error) F {
 	}
 }
 
+// FromS parses a decimal string, as returned by the exchange, without loss of precision
 func FromS(s string) F {
-	f, err := strconv.ParseFloat(s, 64)
+	d, err := decimal.NewFromString(s)
 	if err == nil {
 		return F{
-			V: f,
+			V: d,
 		}
 	}
 	return F{
@@ -207,12 +259,12 @@ func FromS(s string) F {
 
 func FromF(f float64) F {
 	return F{
-		V: f,
+		V: decimal.NewFromFloat(f),
 	}
 }
 
 func FromI(i int) F {
 	return F{
-		V: float64(i),
+		V: decimal.NewFromInt(int64(i)),
 	}
 }
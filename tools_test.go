@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFromS(t *testing.T) {
+	tests := []struct {
+		s       string
+		compact string
+		fixed   string
+		valid   bool
+	}{
+		{"0.00001000", "0.00001", "0.00001000", true},
+		{"12345678.12345678", "12345678.12345678", "12345678.12345678", true},
+		{"0.1", "0.1", "0.10000000", true},
+		{"-3", "-3", "-3.00000000", true},
+		{"", "", "", false},
+		{"abc", "", "", false},
+	}
+	for _, test := range tests {
+		f := FromS(test.s)
+		if f.Valid() != test.valid {
+			t.Errorf("%q: expected valid %v, got %v", test.s, test.valid, f.Err)
+			continue
+		}
+		if !test.valid {
+			continue
+		}
+		if f.StringCompact() != test.compact {
+			t.Errorf("%q: expected compact %v, got %v", test.s, test.compact, f.StringCompact())
+		}
+		if f.String() != test.fixed {
+			t.Errorf("%q: expected %v, got %v", test.s, test.fixed, f.String())
+		}
+	}
+}
+
+func TestDecimalArithmetic(t *testing.T) {
+	tests := []struct {
+		name     string
+		result   F
+		expected string
+	}{
+		// exact, where float64 gives 0.30000000000000004
+		{"add", FromS("0.1").Add(FromS("0.2")), "0.3"},
+		{"sub", FromS("0.3").Sub(FromS("0.1")), "0.2"},
+		{"mult", FromS("0.00001").Mult(FromS("100000")), "1"},
+		{"div", FromS("1").Div(FromS("8")), "0.125"},
+		{"floor", FromS("12.9").Floor(), "12"},
+		{"floor to step", FromS("12.34567").FloorTo(FromS("0.01")), "12.34"},
+		{"ceil to step", FromS("12.34167").CeilTo(FromS("0.01")), "12.35"},
+		{"floor to zero step", FromS("12.34567").FloorTo(FromI(0)), "12.34567"},
+		{"from int", FromI(42), "42"},
+	}
+	for _, test := range tests {
+		if !test.result.Valid() || test.result.Cmp(FromS(test.expected)) != 0 {
+			t.Errorf("%v: expected %v, got %v", test.name, test.expected, test.result)
+		}
+	}
+}
+
+func TestDecimalErrors(t *testing.T) {
+	failure := FromError(errors.New("failure"))
+	tests := []struct {
+		name   string
+		result F
+		err    string
+	}{
+		{"division by zero", FromI(1).Div(FromI(0)), "division by zero"},
+		{"invalid left", failure.Add(FromI(1)), "failure"},
+		{"invalid right", FromI(1).Mult(failure), "failure"},
+		{"first error wins", FromI(1).Div(FromI(0)).Sub(failure), "division by zero"},
+		{"invalid step", FromI(1).FloorTo(failure), "failure"},
+	}
+	for _, test := range tests {
+		if test.result.Valid() || test.result.Err.Error() != test.err {
+			t.Errorf("%v: expected error %q, got %v", test.name, test.err, test.result)
+		}
+		if test.result.String() != test.err {
+			t.Errorf("%v: expected the error as string, got %v", test.name, test.result.String())
+		}
+	}
+}
+
+func TestFormatting(t *testing.T) {
+	tests := []struct {
+		name     string
+		result   string
+		expected string
+	}{
+		{"percent", FromS("0.1234").FormatPercent(), "12.34%"},
+		{"negative percent", FromS("-0.05").FormatPercent(), "-5.00%"},
+		{"int", FromS("12.6").StringInt(), "13"},
+		{"price", FromS("0.0000123").StringPrice(), "0.00001230"},
+	}
+	for _, test := range tests {
+		if test.result != test.expected {
+			t.Errorf("%v: expected %v, got %v", test.name, test.expected, test.result)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// trailInterval is the time between two price checks of the trailing stop
+const trailInterval = 5 * time.Second
+
+// trailingStop follows the price of a symbol in the background and sells the free balance,
+// once the price drops by percent below the highest price since activation.
+type trailingStop struct {
+	symbol  string
+	percent F
+	stop    chan struct{}
+}
+
+// Trail starts a trailing stop for the selected symbol, e.g. "trail 5" for 5%.
+// "trail off" stops it and "trail" without argument shows the current one.
+func (sess *Session) Trail(arg string) {
+	switch arg {
+	case "":
+		if sess.trailing == nil {
+			sess.Answer("no trailing stop active")
+			return
+		}
+		sess.Answerf("trailing stop of %v%% active for %v", sess.trailing.percent.StringCompact(), sess.trailing.symbol)
+		return
+	case "off":
+		if sess.trailing == nil {
+			sess.Answer("no trailing stop active")
+			return
+		}
+		sess.stopTrailing()
+		return
+	}
+
+	if sess.selected == "" {
+		sess.Answer("NO SYMBOL SELECTED!")
+		return
+	}
+	percent := FromS(arg)
+	if !percent.Valid() || percent.Sign() <= 0 || percent.Cmp(FromI(100)) >= 0 {
+		sess.Answerf("INVALID PERCENT: %q", arg)
+		return
+	}
+
+	if sess.trailing != nil {
+		sess.stopTrailing()
+	}
+	sess.trailing = &trailingStop{
+		symbol:  sess.selected,
+		percent: percent,
+		stop:    make(chan struct{}),
+	}
+	go sess.runTrailing(sess.trailing)
+}
+
+func (sess *Session) stopTrailing() {
+	close(sess.trailing.stop)
+	sess.Answerf("trailing stop for %v stopped", sess.trailing.symbol)
+	sess.trailing = nil
+}
+
+// runTrailing watches the price until the trailing stop is hit or stopped
+func (sess *Session) runTrailing(t *trailingStop) {
+	ticker := time.NewTicker(trailInterval)
+	defer ticker.Stop()
+
+	factor := FromI(1).Sub(t.percent.Div(FromI(100)))
+	var high, exit F
+	for {
+		price := sess.livePrice(t.symbol)
+		switch {
+		case !price.Valid():
+			sess.Answerf("TRAIL %v: ERROR ON PRICE UPDATE: %v", t.symbol, price)
+		case high.Sign() == 0 || price.Cmp(high) > 0:
+			high = price
+			exit = high.Mult(factor)
+			sess.Answerf("trail %v: high %v, exit below %v", t.symbol, high, exit)
+		case price.Cmp(exit) <= 0:
+			sess.Answerf("trail %v: price %v dropped below %v, selling", t.symbol, price, exit)
+			sess.tasks <- func() {
+				sess.trailExit(t, price)
+			}
+			return
+		}
+
+		select {
+		case <-t.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// trailExit sells the free balance at the price, which hit the trailing stop.
+// If the sell fails, the trailing stop is started again from the current price.
+// In dry-run mode, the trailing stop ends after the exit is printed.
+// It is executed by the dispatcher.
+func (sess *Session) trailExit(t *trailingStop, price F) {
+	if sess.trailing != t {
+		// stopped in the meantime
+		return
+	}
+	sess.command = fmt.Sprintf("trail %v%% exit at %v", t.percent.StringCompact(), price)
+	sess.Answerf("\n-------- trail exit ------")
+	// nobody may be there to confirm the exit, which reduces the risk and is not limited
+	sess.confirmed = true
+	sess.exiting = true
+	switch {
+	case sess.sellAll(price):
+	case sess.dryRun:
+		sess.Answerf("trailing stop for %v ended, the exit was not sent in dry run", t.symbol)
+	default:
+		sess.Answerf("!!! TRAIL %v: EXIT FAILED, NOTHING SOLD! THE TRAILING STOP IS RESTARTED FROM %v, CHECK YOUR ORDERS !!!", t.symbol, price)
+		go sess.runTrailing(t)
+		return
+	}
+	sess.trailing = nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/smancke/trading-shell/binancefake"
+	"github.com/smancke/trading-shell/config"
+)
+
+// trailExit runs the exit of a trailing stop for the selected symbol and returns the output
+func (shell *testShell) trailExit(trailing *trailingStop, price string) string {
+	return shell.task("trail exit", func() {
+		shell.sess.trailing = trailing
+		shell.sess.trailExit(trailing, FromS(price))
+	})
+}
+
+func TestTrailExitFailed(t *testing.T) {
+	shell := newTestShell(t, nil)
+	shell.run("xyzbtc")
+
+	// nothing to sell
+	trailing := &trailingStop{symbol: "XYZBTC", percent: FromI(5), stop: make(chan struct{})}
+	defer close(trailing.stop)
+	if out := shell.trailExit(trailing, "0.00000900"); !strings.Contains(out, "EXIT FAILED") {
+		t.Errorf("expected the failed exit to be reported, got:\n%v", out)
+	}
+	if out := shell.run("trail"); !strings.Contains(out, "trailing stop of 5% active for XYZBTC") {
+		t.Errorf("expected the trailing stop to stay active, got:\n%v", out)
+	}
+}
+
+func TestTrailExit(t *testing.T) {
+	shell := newTestShell(t, func(srv *binancefake.Server, c *config.Config) {
+		srv.SetBalance("XYZ", "100", "0")
+	})
+	shell.run("xyzbtc")
+
+	trailing := &trailingStop{symbol: "XYZBTC", percent: FromI(5), stop: make(chan struct{})}
+	shell.trailExit(trailing, "0.00000900")
+	// the limit is below the current price of the fake and filled at once
+	orders := shell.srv.Orders()
+	if len(orders) != 1 || orders[0].Price != "0.00000900" || FromS(orders[0].OrigQuantity).Cmp(FromI(100)) != 0 {
+		t.Errorf("expected a sell of 100 XYZ at 0.00000900, got %+v", orders)
+	}
+	if out := shell.run("trail"); !strings.Contains(out, "no trailing stop active") {
+		t.Errorf("expected the trailing stop to be done, got:\n%v", out)
+	}
+}
+
+func TestTrailExitDryRun(t *testing.T) {
+	shell := newTestShell(t, func(srv *binancefake.Server, c *config.Config) {
+		srv.SetBalance("XYZ", "100", "0")
+		c.DryRun = true
+	})
+	shell.run("xyzbtc")
+
+	trailing := &trailingStop{symbol: "XYZBTC", percent: FromI(5), stop: make(chan struct{})}
+	if out := shell.trailExit(trailing, "0.00000900"); !strings.Contains(out, "DRY RUN: SELL LIMIT 100 XYZBTC") {
+		t.Errorf("expected the exit to be printed, got:\n%v", out)
+	}
+	if out := shell.run("trail"); !strings.Contains(out, "no trailing stop active") {
+		t.Errorf("expected the trailing stop to end, got:\n%v", out)
+	}
+	if len(shell.srv.Orders()) != 0 {
+		t.Errorf("expected no orders, got %+v", shell.srv.Orders())
+	}
+}
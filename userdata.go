@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/adshao/go-binance/v2"
+)
+
+// subscribeUserData opens the user data stream of the account
+// and reports order executions and balance changes asynchronously.
+func (sess *Session) subscribeUserData() {
+	if !sess.streaming {
+		return
+	}
+
+	filled := false // balances are only reported after a fill
+	doneC, _, err := sess.exchange.UserDataStream(
+		func(event *binance.WsUserDataEvent) {
+			switch event.Event {
+			case binance.UserDataEventTypeExecutionReport:
+				if msg := orderUpdateMessage(&event.OrderUpdate); msg != "" {
+					sess.Answer(msg)
+				}
+				if event.OrderUpdate.ExecutionType == "TRADE" {
+					filled = true
+					// the fill is journaled after the command, which placed the order
+					update := event.OrderUpdate
+					sess.tasks <- func() {
+						if err := sess.journal.fill(&update); err != nil {
+							sess.Answerf("ERROR ON WRITING JOURNAL: %v", err)
+						}
+					}
+				}
+			case binance.UserDataEventTypeOutboundAccountPosition:
+				if !filled {
+					return
+				}
+				filled = false
+				for _, b := range event.AccountUpdate.WsAccountUpdates {
+					sess.Answerf("  %v: %v (locked %v)", b.Asset, FromS(b.Free).StringCompact(), FromS(b.Locked).StringCompact())
+				}
+			case binance.UserDataEventTypeBalanceUpdate:
+				sess.Answerf("BALANCE %v %v", event.BalanceUpdate.Asset, event.BalanceUpdate.Change)
+			}
+		},
+		func(err error) {
+			sess.Answerf("ERROR ON USER DATA STREAM: %v", err)
+		})
+	if err != nil {
+		sess.Answerf("USER DATA STREAM NOT AVAILABLE: %v", err)
+		return
+	}
+
+	go func() {
+		<-doneC
+		sess.Answer("USER DATA STREAM CLOSED: fills are only shown by history")
+	}()
+}
+
+// orderUpdateMessage formats an execution report, e.g. "SELL 250 XYZBTC @0.0000123 FILLED".
+// New and canceled orders are not reported, because they are the result of the own commands.
+func orderUpdateMessage(o *binance.WsOrderUpdate) string {
+	switch o.ExecutionType {
+	case "TRADE":
+		qty := FromS(o.LatestVolume).StringCompact()
+		if o.Status == string(binance.OrderStatusTypePartiallyFilled) {
+			qty = fmt.Sprintf("%v of %v", qty, FromS(o.Volume).StringCompact())
+		}
+		return fmt.Sprintf("%v %v %v @%v %v", o.Side, qty, o.Symbol, o.LatestPrice, o.Status)
+	case "EXPIRED", "REJECTED":
+		return fmt.Sprintf("%v %v %v @%v %v", o.Side, FromS(o.Volume).StringCompact(), o.Symbol, o.Price, o.Status)
+	}
+	return ""
+}
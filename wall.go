@@ -0,0 +1,327 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/adshao/go-binance/v2"
+)
+
+const (
+	spacingLinear    = "linear"    // equal price distance between the steps
+	spacingGeometric = "geometric" // equal percentage distance between the steps
+
+	distributionEqual      = "equal"      // same quantity on every step
+	distributionIncreasing = "increasing" // more quantity on the higher prices
+	distributionDecreasing = "decreasing" // more quantity on the lower prices
+
+	maxWallSteps = 50
+)
+
+// wallShape describes how a wall of limit orders is spread over a price range
+type wallShape struct {
+	steps        int
+	spacing      string
+	distribution string
+}
+
+func defaultWallShape() wallShape {
+	return wallShape{
+		steps:        4,
+		spacing:      spacingLinear,
+		distribution: distributionEqual,
+	}
+}
+
+func (shape wallShape) String() string {
+	return fmt.Sprintf("%v steps, %v, %v", shape.steps, shape.spacing, shape.distribution)
+}
+
+// set changes the property of the shape, which matches the value:
+// number of steps, spacing or distribution name
+func (shape *wallShape) set(value string) error {
+	if shape.setSpacing(value) == nil || shape.setDistribution(value) == nil {
+		return nil
+	}
+	if _, err := strconv.Atoi(value); err != nil {
+		return fmt.Errorf("not a number of steps, spacing (linear, geometric) or distribution (equal, increasing, decreasing): %q", value)
+	}
+	return shape.setSteps(value)
+}
+
+// setParam changes the property by name, e.g. "spacing" to "geometric"
+func (shape *wallShape) setParam(param, value string) error {
+	switch param {
+	case "steps":
+		return shape.setSteps(value)
+	case "spacing":
+		return shape.setSpacing(value)
+	case "distribution":
+		return shape.setDistribution(value)
+	}
+	return fmt.Errorf("unknown wall parameter: %q", param)
+}
+
+func (shape *wallShape) setSteps(value string) error {
+	steps, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("not a number of steps: %q", value)
+	}
+	if steps < 1 || steps > maxWallSteps {
+		return fmt.Errorf("number of steps has to be between 1 and %v", maxWallSteps)
+	}
+	shape.steps = steps
+	return nil
+}
+
+func (shape *wallShape) setSpacing(value string) error {
+	switch value = strings.ToLower(value); value {
+	case spacingLinear, spacingGeometric:
+		shape.spacing = value
+		return nil
+	}
+	return fmt.Errorf("spacing has to be %v or %v: %q", spacingLinear, spacingGeometric, value)
+}
+
+func (shape *wallShape) setDistribution(value string) error {
+	switch value = strings.ToLower(value); value {
+	case distributionEqual, distributionIncreasing, distributionDecreasing:
+		shape.distribution = value
+		return nil
+	}
+	return fmt.Errorf("distribution has to be %v, %v or %v: %q", distributionEqual, distributionIncreasing, distributionDecreasing, value)
+}
+
+// wallOrder is one limit order of a wall
+type wallOrder struct {
+	price F
+	qty   F
+	err   error // the order does not satisfy the symbol filters
+}
+
+// prices returns the limit prices of the steps from low to high.
+// A wall starts one step above low, while a ladder includes both ends.
+func (shape wallShape) prices(low, high F, includeLow bool) []F {
+	intervals, offset := shape.steps, 1
+	if includeLow {
+		intervals, offset = shape.steps-1, 0
+	}
+	if intervals == 0 {
+		return []F{high}
+	}
+
+	prices := make([]F, shape.steps)
+	for i := range prices {
+		step := i + offset
+		if shape.spacing == spacingGeometric {
+			prices[i] = low.Mult(high.Div(low).Pow(float64(step) / float64(intervals)))
+		} else {
+			prices[i] = low.Add(high.Sub(low).Mult(FromI(step)).Div(FromI(intervals)))
+		}
+	}
+	return prices
+}
+
+// shares returns the part of the total amount for every step, according to the distribution
+func (shape wallShape) shares() []F {
+	weights := make([]F, shape.steps)
+	total := FromI(0)
+	for i := range weights {
+		switch shape.distribution {
+		case distributionIncreasing:
+			weights[i] = FromI(i + 1)
+		case distributionDecreasing:
+			weights[i] = FromI(shape.steps - i)
+		default:
+			weights[i] = FromI(1)
+		}
+		total = total.Add(weights[i])
+	}
+	for i := range weights {
+		weights[i] = weights[i].Div(total)
+	}
+	return weights
+}
+
+// orders spreads the quantity over sell limits of the steps from above low up to high.
+// Prices and quantities are rounded to the filters.
+func (shape wallShape) orders(qty, low, high F, filters SymbolFilters) []wallOrder {
+	shares := shape.shares()
+	orders := make([]wallOrder, shape.steps)
+	for i, price := range shape.prices(low, high, false) {
+		o := wallOrder{
+			price: filters.Price(price, binance.SideTypeSell),
+			qty:   filters.Quantity(qty.Mult(shares[i])),
+		}
+		o.err = filters.Check(o.qty, o.price)
+		orders[i] = o
+	}
+	return orders
+}
+
+// ladder spreads the quote amount over buy limits of the steps from low to high.
+// Prices and quantities are rounded to the filters.
+func (shape wallShape) ladder(quoteQty, low, high F, filters SymbolFilters) []wallOrder {
+	shares := shape.shares()
+	orders := make([]wallOrder, shape.steps)
+	for i, price := range shape.prices(low, high, true) {
+		o := wallOrder{
+			price: filters.Price(price, binance.SideTypeBuy),
+		}
+		o.qty = filters.Quantity(quoteQty.Mult(shares[i]).Div(o.price))
+		o.err = filters.Check(o.qty, o.price)
+		orders[i] = o
+	}
+	return orders
+}
+
+// showWall prints the orders of a wall as table, with the change relative to the basePrice
+func (sess *Session) showWall(orders []wallOrder) {
+	sess.Answerf("%4v %12v %9v %16v %12v %9v", "step", "price", "base", "qty", "value", sess.currency)
+	totalQty := FromI(0)
+	totalValue := FromI(0)
+	for i, o := range orders {
+		value := o.qty.Mult(o.price)
+		line := fmt.Sprintf("%4v %12v %9v %16v %12v %9v", i+1, o.price, o.price.Sub(sess.basePrice).Div(sess.basePrice).FormatPercent(), o.qty.StringCompact(), value, sess.formatValue(value.Mult(sess.quoteRate)))
+		if o.err != nil {
+			line += fmt.Sprintf(" NOT POSSIBLE: %v", o.err)
+		} else {
+			totalQty = totalQty.Add(o.qty)
+			totalValue = totalValue.Add(value)
+		}
+		sess.Answer(line)
+	}
+	sess.Answerf("%4v %12v %9v %16v %12v %9v", "", "", "total", totalQty.StringCompact(), totalValue, sess.formatValue(totalValue.Mult(sess.quoteRate)))
+}
+
+// sellWallArgs parses the arguments of the sell wall: the first number is the max multiplier,
+// the other arguments change the shape, e.g. "4.5 6 geometric increasing"
+func (sess *Session) sellWallArgs(arg string) (maxMult F, shape wallShape, err error) {
+	maxMult = sess.sellMaxMult
+	shape = sess.wallShape
+	for i, a := range strings.Fields(arg) {
+		if mult := FromS(a); i == 0 && mult.Valid() {
+			if mult.Sign() <= 0 {
+				return maxMult, shape, fmt.Errorf("invalid multiplier: %q", a)
+			}
+			maxMult = mult
+			continue
+		}
+		if err := shape.set(a); err != nil {
+			return maxMult, shape, err
+		}
+	}
+	return maxMult, shape, nil
+}
+
+// SellWall cancels all orders and spreads the free balance over sell limits
+// above the basePrice up to the max multiplier, e.g. "4.5 6 geometric increasing"
+func (sess *Session) SellWall(arg string) {
+	if sess.selected == "" {
+		sess.Answer("NO SYMBOL SELECTED!")
+		return
+	}
+	maxMult, shape, err := sess.sellWallArgs(arg)
+	if err != nil {
+		sess.Answerf("INVALID SELL WALL: %v", err)
+		return
+	}
+
+	// the wall is shown and confirmed for the whole balance, before the orders are canceled
+	low, high := sess.basePrice, sess.basePrice.Mult(maxMult)
+	free, locked := sess.balance()
+	orders := shape.orders(free.Add(locked), low, high, sess.filters())
+	sess.showWall(orders)
+	if !sess.approveReplacing(sess.wallPreviews(binance.SideTypeSell, orders)...) {
+		return
+	}
+
+	sess.CancelAllOrders()
+	free, locked = sess.balance()
+	if locked.Sign() != 0 {
+		sess.Answerf("WARNING: locked balance of %v not in order!", locked)
+	}
+
+	orders = shape.orders(free, low, high, sess.filters())
+	for i := len(orders) - 1; i >= 0; i-- {
+		if orders[i].err != nil {
+			continue
+		}
+		if !sess.placeLimitOrder(binance.SideTypeSell, orders[i].qty, orders[i].price) {
+			return
+		}
+	}
+}
+
+// SellWallPreview shows the orders of a sell wall without placing them.
+// The whole balance is used, because the sell wall cancels the open orders first.
+func (sess *Session) SellWallPreview(arg string) {
+	if sess.selected == "" {
+		sess.Answer("NO SYMBOL SELECTED!")
+		return
+	}
+	maxMult, shape, err := sess.sellWallArgs(arg)
+	if err != nil {
+		sess.Answerf("INVALID SELL WALL: %v", err)
+		return
+	}
+
+	free, locked := sess.balance()
+	sess.Answerf("%v, up to %v", shape, maxMult.FormatPercent())
+	sess.showWall(shape.orders(free.Add(locked), sess.basePrice, sess.basePrice.Mult(maxMult), sess.filters()))
+}
+
+// buyLadderArgs parses the arguments of the buy ladder: the lowest and highest multiplier,
+// followed by optional changes of the shape, e.g. "0.8 1 5 geometric decreasing"
+func (sess *Session) buyLadderArgs(arg string) (lowMult, highMult F, shape wallShape, err error) {
+	args := strings.Fields(arg)
+	if len(args) < 2 {
+		return lowMult, highMult, shape, fmt.Errorf("missing multipliers")
+	}
+	lowMult, highMult = FromS(args[0]), FromS(args[1])
+	if !lowMult.Valid() || !highMult.Valid() || lowMult.Sign() <= 0 || highMult.Cmp(lowMult) <= 0 {
+		return lowMult, highMult, shape, fmt.Errorf("invalid multipliers, the first has to be positive and below the second: %q %q", args[0], args[1])
+	}
+	shape = sess.wallShape
+	for _, a := range args[2:] {
+		if err := shape.set(a); err != nil {
+			return lowMult, highMult, shape, err
+		}
+	}
+	return lowMult, highMult, shape, nil
+}
+
+// BuyLadder splits the invest amount over buy limits between two multipliers of the basePrice,
+// e.g. "0.8 1" or "0.8 1 5 geometric decreasing"
+func (sess *Session) BuyLadder(arg string) {
+	if sess.selected == "" {
+		sess.Answer("NO SYMBOL SELECTED!")
+		return
+	}
+	lowMult, highMult, shape, err := sess.buyLadderArgs(arg)
+	if err != nil {
+		sess.Answerf("USAGE: buy-ladder <low multiplier> <high multiplier> [steps] [spacing] [distribution]: %v", err)
+		return
+	}
+
+	quoteQty := sess.maxInvest.Div(sess.quoteRate)
+	orders := shape.ladder(quoteQty, sess.basePrice.Mult(lowMult), sess.basePrice.Mult(highMult), sess.filters())
+	sess.showWall(orders)
+	if !sess.approve(sess.wallPreviews(binance.SideTypeBuy, orders)...) {
+		return
+	}
+
+	committed := FromI(0)
+	for i := len(orders) - 1; i >= 0; i-- {
+		if orders[i].err != nil {
+			continue
+		}
+		if !sess.placeLimitOrder(binance.SideTypeBuy, orders[i].qty, orders[i].price) {
+			break
+		}
+		committed = committed.Add(orders[i].qty.Mult(orders[i].price))
+	}
+	_, quoteAsset := sess.assets()
+	sess.Answerf("committed: %v of %v %v / %v", committed, quoteQty.FloorTo(minPrecision), quoteAsset, sess.formatValue(committed.Mult(sess.quoteRate)))
+}
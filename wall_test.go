@@ -0,0 +1,110 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/adshao/go-binance/v2"
+)
+
+// approx checks the values with a tolerance for the float64 calculation of geometric steps
+func approx(values []F, expected ...string) bool {
+	if len(values) != len(expected) {
+		return false
+	}
+	for i, v := range values {
+		if !v.Valid() || !v.V.Sub(FromS(expected[i]).V).Abs().LessThan(FromS("0.000000001").V) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestWallPrices(t *testing.T) {
+	tests := []struct {
+		shape      wallShape
+		low, high  string
+		includeLow bool
+		expected   []string
+	}{
+		{wallShape{4, spacingLinear, distributionEqual}, "1", "2", false, []string{"1.25", "1.5", "1.75", "2"}},
+		{wallShape{3, spacingLinear, distributionEqual}, "1", "2", true, []string{"1", "1.5", "2"}},
+		{wallShape{2, spacingGeometric, distributionEqual}, "1", "4", false, []string{"2", "4"}},
+		{wallShape{3, spacingGeometric, distributionEqual}, "1", "4", true, []string{"1", "2", "4"}},
+		{wallShape{1, spacingLinear, distributionEqual}, "1", "2", false, []string{"2"}},
+		{wallShape{1, spacingLinear, distributionEqual}, "1", "2", true, []string{"2"}},
+	}
+	for _, test := range tests {
+		prices := test.shape.prices(FromS(test.low), FromS(test.high), test.includeLow)
+		if !approx(prices, test.expected...) {
+			t.Errorf("%v from %v to %v (include low %v): expected %v, got %v", test.shape, test.low, test.high, test.includeLow, test.expected, prices)
+		}
+	}
+}
+
+func TestWallShares(t *testing.T) {
+	tests := []struct {
+		shape    wallShape
+		expected []string
+	}{
+		{wallShape{4, spacingLinear, distributionEqual}, []string{"0.25", "0.25", "0.25", "0.25"}},
+		{wallShape{4, spacingLinear, distributionIncreasing}, []string{"0.1", "0.2", "0.3", "0.4"}},
+		{wallShape{4, spacingLinear, distributionDecreasing}, []string{"0.4", "0.3", "0.2", "0.1"}},
+		{wallShape{1, spacingLinear, distributionIncreasing}, []string{"1"}},
+	}
+	for _, test := range tests {
+		if shares := test.shape.shares(); !approx(shares, test.expected...) {
+			t.Errorf("%v: expected %v, got %v", test.shape, test.expected, shares)
+		}
+	}
+}
+
+func TestWallOrders(t *testing.T) {
+	filters := testFilters()
+	shape := wallShape{4, spacingLinear, distributionEqual}
+
+	orders := shape.orders(FromS("100.5"), FromS("0.000010"), FromS("0.000020"), filters)
+	expectedPrices := []string{"0.0000125", "0.000015", "0.0000175", "0.00002"}
+	for i, o := range orders {
+		// the quantity is rounded down to the step size, the sell prices up to the tick size
+		if o.err != nil || o.qty.Cmp(FromS("25.1")) != 0 || o.price.Cmp(FromS(expectedPrices[i]).CeilTo(filters.TickSize)) != 0 {
+			t.Errorf("sell step %v: unexpected order %v @%v (%v)", i+1, o.qty, o.price, o.err)
+		}
+	}
+
+	// steps below the min notional are marked as not possible
+	orders = shape.orders(FromS("20"), FromS("0.000010"), FromS("0.000020"), filters)
+	if orders[0].err == nil {
+		t.Errorf("expected the order value of %v @%v to be too small", orders[0].qty, orders[0].price)
+	}
+
+	ladder := shape.ladder(FromS("0.001"), FromS("0.000010"), FromS("0.000020"), filters)
+	for i, o := range ladder {
+		value := o.qty.Mult(o.price)
+		if o.err != nil || o.price.Cmp(filters.Price(o.price, binance.SideTypeBuy)) != 0 || value.Cmp(FromS("0.00025")) > 0 || value.Cmp(FromS("0.00024")) < 0 {
+			t.Errorf("buy step %v: unexpected order %v @%v (%v)", i+1, o.qty, o.price, o.err)
+		}
+	}
+}
+
+func TestWallShapeSet(t *testing.T) {
+	shape := defaultWallShape()
+	for _, value := range []string{"6", "Geometric", "increasing"} {
+		if err := shape.set(value); err != nil {
+			t.Errorf("%v: unexpected error %v", value, err)
+		}
+	}
+	if shape != (wallShape{6, spacingGeometric, distributionIncreasing}) {
+		t.Errorf("unexpected shape %v", shape)
+	}
+	for _, value := range []string{"0", "51", "fancy"} {
+		if err := shape.set(value); err == nil {
+			t.Errorf("%v: expected an error", value)
+		}
+	}
+	if err := shape.setParam("spacing", "linear"); err != nil || shape.spacing != spacingLinear {
+		t.Errorf("unexpected result of setting the spacing: %v %v", shape, err)
+	}
+	if err := shape.setParam("color", "red"); err == nil {
+		t.Errorf("expected an error for an unknown parameter")
+	}
+}